@@ -0,0 +1,660 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// IsDecimalRepresentable returns true if the fraction num/den is exactly
+// representable as a float64.
+//
+// After reducing num/den to lowest terms, den must be a power of two and
+// num, with its trailing zero bits removed, must fit in 53 bits.
+// For int64 inputs the binary exponent of num/den is in [-63, 62], so
+// the float64 exponent range, including subnormals, is never a limit.
+// Special cases:
+// IsDecimalRepresentable(x, 0)      = false
+// IsDecimalRepresentable(0, x)      = true     x != 0
+// IsDecimalRepresentable(3, 8)      = true
+// IsDecimalRepresentable(1, 10)     = false
+// IsDecimalRepresentable(2^53+1, 1) = false
+//
+func IsDecimalRepresentable(num, den int64) bool {
+	if den == 0 {
+		return false
+	}
+	r := new(big.Rat).SetFrac64(num, den)      // SetFrac64 reduces to lowest terms
+	d := r.Denom()
+	if d.BitLen()-1 != int(d.TrailingZeroBits()) {
+		return false                           // den is not a power of two
+	}
+	n := new(big.Int).Abs(r.Num())
+	if n.Sign() == 0 {
+		return true
+	}
+	return n.BitLen()-int(n.TrailingZeroBits()) <= 53
+}
+
+// ExpectedRounded returns the correctly rounded (to nearest, ties to even)
+// float64 result of a op b, op is one of '+', '-', '*' and '/'.
+//
+// The operation is done exactly with big.Rat and the result is rounded once.
+// This is a reference for testing that hardware or emulated results are
+// correctly rounded. It is slow.
+// If a or b is Inf or NaN, or in division b is zero, no rounding is involved
+// and the IEEE 754 result a op b is returned: x/+/-0 = +/-Inf, 0/0 = NaN,
+// Inf-Inf = NaN, 0*Inf = NaN, etc. A zero result has the IEEE 754 sign.
+// For other op's ExpectedRounded returns NaN.
+//
+func ExpectedRounded(a, b float64, op byte) float64 {
+	if !IsFinite(a) || !IsFinite(b) || op == '/' && b == 0 {
+		return ieeeOp(a, b, op)
+	}
+	r, ok := exactOp(a, b, op)
+	if !ok {
+		return math.NaN()
+	}
+	f, _ := r.Float64()
+	if f == 0 {
+		switch {
+		case r.Sign() < 0:
+			f = math.Copysign(0, -1)
+		case r.Sign() == 0:
+			f = ieeeOp(a, b, op)         // exact zero, only the sign is at stake
+		}
+	}
+	return f
+}
+
+// exactOp returns a op b as an exact big.Rat for finite a and b.
+// ok is false for an unknown op or division by zero.
+func exactOp(a, b float64, op byte) (r *big.Rat, ok bool) {
+	x := new(big.Rat).SetFloat64(a)
+	y := new(big.Rat).SetFloat64(b)
+	switch op {
+	case '+':
+		return x.Add(x, y), true
+	case '-':
+		return x.Sub(x, y), true
+	case '*':
+		return x.Mul(x, y), true
+	case '/':
+		if b == 0 {
+			return nil, false
+		}
+		return x.Quo(x, y), true
+	}
+	return nil, false
+}
+
+func ieeeOp(a, b float64, op byte) float64 {
+	switch op {
+	case '+':
+		return a + b
+	case '-':
+		return a - b
+	case '*':
+		return a * b
+	case '/':
+		return a / b
+	}
+	return math.NaN()
+}
+
+// SqrtIsCorrectlyRounded returns true if result is the correctly rounded
+// square root of x.
+//
+// For positive finite x, the squares of the midpoints between result and its
+// neighbours are computed exactly with big.Float and x must be between them.
+// A midpoint has 54 significant bits and the square root of a float64 is
+// never exactly a midpoint, so there are no ties.
+// Special cases, result must be bit-equal to:
+// x = +0, -0, +Inf  ->  x
+// x < 0, NaN        ->  any NaN
+//
+func SqrtIsCorrectlyRounded(x, result float64) bool {
+	switch {
+	case x != x || x < 0:
+		return result != result
+	case x == 0 || x > math.MaxFloat64:
+		return math.Float64bits(result) == math.Float64bits(x)
+	case !(result > 0) || result > math.MaxFloat64:
+		return false
+	}
+	lo, hi := midpoints(result)
+	lo.Mul(lo, lo)
+	hi.Mul(hi, hi)
+	bx := new(big.Float).SetFloat64(x)
+	return lo.Cmp(bx) <= 0 && bx.Cmp(hi) <= 0
+}
+
+// CbrtIsCorrectlyRounded returns true if result is the correctly rounded
+// real cube root of x.
+//
+// As in SqrtIsCorrectlyRounded, the cubes of the midpoints between
+// abs(result) and its neighbours are computed exactly with big.Float and
+// abs(x) must be between them, and result must have the sign of x. A cube
+// root is never subnormal and never exactly a midpoint, so there are no ties.
+// math.Cbrt is not correctly rounded for all x, its error is < 0.667 ulps.
+// Special cases, result must be bit-equal to:
+// x = +/-0, +/-Inf  ->  x
+// x = NaN           ->  any NaN
+//
+func CbrtIsCorrectlyRounded(x, result float64) bool {
+	switch {
+	case x != x:
+		return result != result
+	case x == 0 || IsInf(x):
+		return math.Float64bits(result) == math.Float64bits(x)
+	case result == 0 || !IsFinite(result) || math.Signbit(x) != math.Signbit(result):
+		return false
+	}
+	lo, hi := midpoints(math.Abs(result))
+	lo.Mul(lo, new(big.Float).Mul(lo, lo))
+	hi.Mul(hi, new(big.Float).Mul(hi, hi))
+	bx := new(big.Float).SetFloat64(math.Abs(x))
+	return lo.Cmp(bx) <= 0 && bx.Cmp(hi) <= 0
+}
+
+// midpoints returns the exact midpoints between positive finite r and its
+// neighbours as big.Floats with 512 bits of precision. The upper
+// midpoint of MaxFloat64 is MaxFloat64 + 2^970, the overflow threshold.
+func midpoints(r float64) (lo, hi *big.Float) {
+	br := new(big.Float).SetPrec(512).SetFloat64(r)
+	lo = new(big.Float).SetPrec(512).SetFloat64(NextToZero(r))
+	lo.Add(lo, br)
+	lo.SetMantExp(lo, -1)
+	hi = new(big.Float).SetPrec(512).SetFloat64(Ulp(r))
+	hi.SetMantExp(hi, -1)
+	hi.Add(hi, br)
+	return lo, hi
+}
+
+// RoundingModeUlpDelta returns the distances in ulps from the round to
+// nearest float64 of exact to the float64's rounded toward zero, up and down.
+//
+// The map keys are the big.RoundingMode names "ToZero", "ToPositiveInf"
+// and "ToNegativeInf". A delta is 0 or 1, it is 0 if the mode rounds to
+// the same float as round to nearest. For an exactly representable exact,
+// including +/-Inf, all deltas are 0. Above the float64 range rounding up
+// gives +Inf, 1 ulp from MaxFloat64.
+//
+func RoundingModeUlpDelta(exact *big.Float) map[string]uint64 {
+	f, acc := exact.Float64()      // nearest, acc is the direction from exact to f
+	up, down := f, f
+	switch acc {
+	case big.Below:
+		up = NextToward(f, math.Inf(1))
+	case big.Above:
+		down = NextToward(f, math.Inf(-1))
+	}
+	zero := down
+	if exact.Sign() < 0 {
+		zero = up
+	}
+	return map[string]uint64{
+		big.ToZero.String():        UlpsBetween(f, zero),
+		big.ToPositiveInf.String(): UlpsBetween(f, up),
+		big.ToNegativeInf.String(): UlpsBetween(f, down),
+	}
+}
+
+// RsqrtError returns the signed error of approx in ulps of the exact
+// 1/sqrt(x), (approx - 1/sqrt(x)) / ulp.
+//
+// 1/sqrt(x) is computed with big.Float to 256 bits. The ulp is that of the
+// binade of 1/sqrt(x), 2^(Log2(1/sqrt(x)) - 52). The correctly rounded
+// 1/sqrt(x) has abs(error) < 0.5. For positive finite x 1/sqrt(x) is in
+// [2^-512, 2^537], always a normal float64.
+// For x <= 0, +Inf or NaN, the IEEE 754 result is not finite and nonzero:
+// 1/sqrt(+0) = +Inf, 1/sqrt(-0) = -Inf, 1/sqrt(+Inf) = +0, NaN for x < 0
+// and NaN. RsqrtError returns 0 if approx is this value and +Inf otherwise.
+// Special cases:
+// RsqrtError(x, NaN)       = NaN     x > 0 finite
+// RsqrtError(x, +/-Inf)    = +/-Inf  x > 0 finite
+//
+func RsqrtError(x, approx float64) float64 {
+	if !(x > 0) || IsInf(x) {
+		want := 1 / math.Sqrt(x)
+		if math.Float64bits(want) == math.Float64bits(approx) || want != want && approx != approx {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	if !IsFinite(approx) {
+		return approx
+	}
+	const prec = 256
+	t := new(big.Float).SetPrec(prec).SetFloat64(x)
+	t.Sqrt(t)
+	t.Quo(new(big.Float).SetPrec(prec).SetInt64(1), t)
+	ulp := new(big.Float).SetMantExp(big.NewFloat(1), t.MantExp(nil) - 1 - 52)
+	e := new(big.Float).SetPrec(prec).SetFloat64(approx)
+	e.Sub(e, t)
+	e.Quo(e, ulp)
+	f, _ := e.Float64()
+	return f
+}
+
+// Reciprocal returns r = 1/x and the signed error of r in ulps of the exact
+// 1/x, (r - 1/x) / ulp.
+//
+// 1/x is computed with big.Float to 256 bits. The ulp is that of the binade
+// of 1/x, but at least 2^-1074. The division is correctly rounded, so
+// abs(ulpErr) <= 0.5, and ulpErr is 0 if 1/x is a float64, as for powers of
+// two. 1/x overflows to +/-Inf for abs(x) <= 2^-1024, a subnormal x.
+// Special cases:
+// Reciprocal(+/-0)     = +/-Inf, 0
+// Reciprocal(+/-Inf)   = +/-0, 0
+// Reciprocal(NaN)      = NaN, NaN
+// Reciprocal(x)        = +/-Inf, +/-Inf   abs(x) <= 2^-1024
+//
+func Reciprocal(x float64) (r, ulpErr float64) {
+	r = 1 / x
+	switch {
+	case x != x:
+		return r, r
+	case x == 0 || IsInf(x):
+		return r, 0
+	case IsInf(r):
+		return r, r
+	}
+	const prec = 256
+	t := new(big.Float).SetPrec(prec).Quo(big.NewFloat(1), big.NewFloat(x))
+	exp := t.MantExp(nil) - 1 - 52
+	if exp < -1074 {
+		exp = -1074
+	}
+	ulp := new(big.Float).SetMantExp(big.NewFloat(1), exp)
+	e := new(big.Float).SetPrec(prec).SetFloat64(r)
+	e.Sub(e, t)
+	e.Quo(e, ulp)
+	ulpErr, _ = e.Float64()
+	return
+}
+
+// UlpsBetweenBig returns the exact distance between x and y in ulps.
+//
+// It is the absolute difference of the ordinals of x and y, the positions
+// of x and y in the ordered sequence of all float64's, computed with big.Int.
+// This is a slow reference for testing UlpsBetween, which must equal the
+// low 64 bits of UlpsBetweenBig for non-NaN arguments.
+// Special cases:
+// UlpsBetweenBig(-0, 0)       = 0
+// UlpsBetweenBig(-Inf, +Inf)  = 2 * 0x7ff0000000000000
+// UlpsBetweenBig(x, NaN)      = nil
+//
+func UlpsBetweenBig(x, y float64) *big.Int {
+	if x != x || y != y {
+		return nil
+	}
+	d := big.NewInt(ordinal(y))
+	d.Sub(d, big.NewInt(ordinal(x)))
+	return d.Abs(d)
+}
+
+// WasRounded returns true if result is not exactly a op b, op is one of
+// '+', '-', '*' and '/'.
+//
+// The exact a op b is computed with big.Rat, so WasRounded(a, b, a+b, '+')
+// tells if the addition lost precision. An overflow to +/-Inf is rounding.
+// The sign of a zero result is not checked.
+// If a or b is Inf or NaN, or in division b is zero, the IEEE 754 result
+// is exact and WasRounded returns false if result is that, any NaN for NaN.
+// For other op's WasRounded returns true, also for NaN operands.
+// Special cases:
+// WasRounded(0.1, 0.2, 0.1+0.2, '+')    = true
+// WasRounded(0.5, 0.25, 0.75, '+')      = false
+// WasRounded(1, 0, Inf, '/')            = false
+// WasRounded(NaN, b, NaN, op)           = false, op one of + - * /
+// WasRounded(a, b, result, '%')         = true, also for NaN a, b, result
+//
+func WasRounded(a, b, result float64, op byte) bool {
+	switch op {
+	case '+', '-', '*', '/':
+	default:
+		return true
+	}
+	if !IsFinite(a) || !IsFinite(b) || op == '/' && b == 0 {
+		e := ieeeOp(a, b, op)
+		return !(e == result || e != e && result != result)
+	}
+	r, _ := exactOp(a, b, op)
+	if !IsFinite(result) {
+		return true
+	}
+	return new(big.Rat).SetFloat64(result).Cmp(r) != 0
+}
+
+// ParseFloatDirected parses the decimal or hexadecimal number s as
+// strconv.ParseFloat(s, 64) does, but rounds it to float64 with the given mode.
+//
+// The nearest float64 f is from strconv.ParseFloat and the exact value of s
+// is a big.Rat. If f is not exact, the directed modes move f one step
+// towards the exact value when needed, with NextToward. big.ToNearestAway
+// moves f only at a tie. A zero result has the sign of s.
+// Malformed s returns 0 and the *strconv.NumError from strconv.ParseFloat.
+// A result of +/-Inf from a finite s returns a *strconv.NumError with
+// strconv.ErrRange, as strconv.ParseFloat does for an overflow. In the
+// modes rounding towards zero a large s gives +/-MaxFloat64 without error.
+// An exponent beyond the big.Rat limit, abs(exponent) > 10^7 or so,
+// is rounded as a value just above MaxFloat64 or just above 0.
+// Underscores between digits are accepted where strconv.ParseFloat
+// accepts them.
+// Special cases:
+// ParseFloatDirected("0.1", big.ToNegativeInf)  = NextToZero(0.1)
+// ParseFloatDirected("0.1", big.ToPositiveInf)  = 0.1
+// ParseFloatDirected("1e400", big.ToZero)       = MaxFloat64, nil
+// ParseFloatDirected("1e-400", big.AwayFromZero) = 2^-1074, nil
+// ParseFloatDirected("inf", mode)               = +Inf, nil
+//
+func ParseFloatDirected(s string, mode big.RoundingMode) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrSyntax {
+		e.Func = "ParseFloatDirected"
+		return 0, e
+	}
+	if f != f || IsInf(f) && err == nil {
+		return f, nil                          // "nan", "inf"
+	}
+	// strconv.ParseFloat has checked the syntax, also the placement of
+	// underscores, so they are only digit separators and can be dropped.
+	cmp := 0                                  // sign of exact - f
+	r, ok := new(big.Rat).SetString(strings.ReplaceAll(s, "_", ""))
+	switch {
+	case IsInf(f):
+		cmp = -Sign(f)
+	case !ok:                                 // f is +/-0, exact is tiny
+		cmp = 1
+		if math.Signbit(f) {
+			cmp = -1
+		}
+	default:
+		cmp = r.Cmp(new(big.Rat).SetFloat64(f))
+	}
+	if cmp != 0 {
+		towards := math.Copysign(math.Inf(1), float64(cmp))
+		g := NextToward(f, towards)           // the other neighbour of exact
+		switch mode {
+		case big.ToNearestAway:
+			if ok && !IsInf(f) && !IsInf(g) && math.Abs(g) > math.Abs(f) {
+				mid := new(big.Rat).SetFloat64(f)
+				mid.Add(mid, new(big.Rat).SetFloat64(g))
+				mid.Quo(mid, big.NewRat(2, 1))
+				if r.Cmp(mid) == 0 {
+					f = g
+				}
+			}
+		case big.ToZero:
+			if f != 0 && (cmp < 0) == (f > 0) {
+				f = g
+			}
+		case big.AwayFromZero:
+			if f == 0 || (cmp > 0) == (f > 0) {
+				f = g
+			}
+		case big.ToNegativeInf:
+			if cmp < 0 {
+				f = g
+			}
+		case big.ToPositiveInf:
+			if cmp > 0 {
+				f = g
+			}
+		}
+	}
+	if IsInf(f) {
+		return f, &strconv.NumError{Func: "ParseFloatDirected", Num: s, Err: strconv.ErrRange}
+	}
+	return f, nil
+}
+
+// SubtractionUnderflowed returns true if x-y is subnormal or zero and
+// not the exact difference of x and y.
+//
+// By Hauser's theorem, with IEEE 754 gradual underflow a difference (or
+// sum) of two floats which is subnormal is always exact: x and y are
+// multiples of 2^-1074, so is x - y, and below 2^-1022 all multiples of
+// 2^-1074 are floats. So SubtractionUnderflowed is false on conforming
+// hardware. It can be true where subnormal results are flushed to zero
+// (FTZ) or subnormal operands are treated as zero (DAZ), as on some GPUs
+// and with some compiler flags. The exact difference is computed with
+// big.Rat only if x-y is below 2^-1022 in magnitude.
+// SubtractionUnderflowed is false if x or y is Inf or NaN.
+//
+func SubtractionUnderflowed(x, y float64) bool {
+	d := x - y
+	if !IsFinite(x) || !IsFinite(y) || x == y || math.Abs(d) >= 0x1p-1022 {
+		return false
+	}
+	e := new(big.Rat).SetFloat64(x)
+	e.Sub(e, new(big.Rat).SetFloat64(y))
+	return e.Cmp(new(big.Rat).SetFloat64(d)) != 0
+}
+
+// RandomOpTriple returns random operands a and b and the correctly rounded
+// result of a op b, ExpectedRounded(a, b, op), op is one of '+', '-', '*'
+// and '/'. The triples are (input, expected) pairs for fuzzing code which
+// depends on arithmetic.
+//
+// a and b are math.Float64frombits of random uint64's. Half of the time a
+// finite b gets an exponent within 32 of the exponent of a, clamped to the
+// finite exponents 0 - 0x7fe, so that sums and differences are not only the
+// larger operand and cancellation occurs. Each operand is an Inf or a NaN
+// with probability 1/2048, the exponent 0x7ff, and the result then follows
+// IEEE 754.
+// For other op's a, b and result are NaN.
+//
+func RandomOpTriple(state *uint64, op byte) (a, b, result float64) {
+	switch op {
+	case '+', '-', '*', '/':
+	default:
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	ua, ub := Splitmix(state), Splitmix(state)
+	if ub&1 == 0 && ub&posInf != posInf {
+		e := int64(ua>>52&0x7ff) + int64(ub>>1&63) - 32
+		if e < 0 {
+			e = 0
+		} else if e > 0x7fe {
+			e = 0x7fe
+		}
+		ub = ub&^(0x7ff<<52) | uint64(e)<<52
+	}
+	a, b = math.Float64frombits(ua), math.Float64frombits(ub)
+	return a, b, ExpectedRounded(a, b, op)
+}
+
+// PowIsCorrectlyRounded returns true if result is the correctly rounded
+// base^exp.
+//
+// The special cases follow the contract of math.Pow, result must be
+// bit-equal to the value there and any NaN for a NaN. Otherwise base is
+// finite nonzero and exp finite, and base < 0 only with an integer exp.
+// The sign of result must be negative just for base < 0 and an odd exp.
+// abs(base)^exp is compared with the midpoints between abs(result) and its
+// neighbours, 2^-1075 for a zero result and the overflow threshold
+// 2^1024 - 2^970 for +/-Inf, through logarithms: exp*ln(abs(base)) against
+// the logs of the midpoints, computed with big.Float to 320 bits.
+// An exact tie, abs(base)^exp a midpoint, is possible for exp = n/2^k:
+// (t^2)^1.5 = t^3 is a midpoint for t = 2^18 - 1. When the logs are too
+// close to call, the tie is checked exactly for k <= 5 and abs(n) <= 4096
+// and the even neighbour is the correct one. It is slow.
+// math.Pow is not correctly rounded, its error is often several ulps.
+//
+func PowIsCorrectlyRounded(base, exp, result float64) bool {
+	if want, ok := powSpecial(base, exp); ok {
+		if want != want {
+			return result != result
+		}
+		return math.Float64bits(want) == math.Float64bits(result)
+	}
+	if result != result || math.Signbit(result) != (base < 0 && isOddInt(exp)) {
+		return false
+	}
+	const prec = 320
+	x := new(big.Float).SetPrec(prec).SetFloat64(math.Abs(base))
+	l := bigLog(x, prec)
+	l.Mul(l, new(big.Float).SetPrec(prec).SetFloat64(exp))     // ln(abs(base)^exp)
+	r := math.Abs(result)
+	even := math.Float64bits(r)&1 == 0 || IsInf(r)
+	var lo, hi *big.Float
+	switch {
+	case r == 0:
+		hi = new(big.Float).SetMantExp(big.NewFloat(1), -1075)
+	case IsInf(r):
+		lo = new(big.Float).SetPrec(prec).SetFloat64(math.MaxFloat64)
+		lo.Add(lo, new(big.Float).SetMantExp(big.NewFloat(1), 970))
+	default:
+		lo, hi = midpoints(r)
+	}
+	// cmp compares abs(base)^exp with m: -1, 0 or +1, 0 for an exact tie.
+	cmp := func(m *big.Float) int {
+		d := bigLog(m, prec)
+		d.Sub(l, d)
+		eps := new(big.Float).SetMantExp(big.NewFloat(1), Log2(1+math.Abs(exp))+21-prec)  // abs(l) < 2^10 * (1+abs(exp))
+		if new(big.Float).Abs(d).Cmp(eps) > 0 || !powExactTie(math.Abs(base), exp, m) {
+			return d.Sign()
+		}
+		return 0
+	}
+	if lo != nil {
+		if c := cmp(lo); c < 0 || c == 0 && !even {
+			return false
+		}
+	}
+	if hi != nil {
+		if c := cmp(hi); c > 0 || c == 0 && !even {
+			return false
+		}
+	}
+	return true
+}
+
+// powSpecial returns math.Pow(x, y) and true for the special cases of the
+// math.Pow contract, and false for finite nonzero x and finite y with
+// x >= 0 or y an integer, which need rounding.
+func powSpecial(x, y float64) (float64, bool) {
+	switch {
+	case y == 0 || x == 1:
+		return 1, true
+	case y == 1:
+		return x, true
+	case x != x || y != y:
+		return math.NaN(), true
+	case x == 0:
+		switch {
+		case y < 0 && isOddInt(y):
+			return math.Copysign(math.Inf(1), x), true
+		case y < 0:
+			return math.Inf(1), true
+		case isOddInt(y):
+			return x, true
+		}
+		return 0, true
+	case IsInf(y):
+		switch {
+		case x == -1:
+			return 1, true
+		case (math.Abs(x) > 1) == (y > 0):
+			return math.Inf(1), true
+		}
+		return 0, true
+	case IsInf(x):
+		if x < 0 {
+			return powSpecial(math.Copysign(0, -1), -y)
+		}
+		if y > 0 {
+			return math.Inf(1), true
+		}
+		return 0, true
+	case x < 0 && y != math.Trunc(y):
+		return math.NaN(), true
+	}
+	return 0, false
+}
+
+// isOddInt returns true if y is an odd integer. Floats >= 2^53 are even.
+func isOddInt(y float64) bool {
+	return math.Abs(y) < 0x1p53 && y == math.Trunc(y) && int64(y)&1 != 0
+}
+
+// bigLog returns ln(x) for x > 0 to prec bits.
+// x = m * 2^e, 0.5 <= m < 1, and ln(x) = e*ln(2) + ln(m), where
+// ln(m) = 2*atanh((m-1)/(m+1)) and ln(2) = 2*atanh(1/3).
+func bigLog(x *big.Float, prec uint) *big.Float {
+	p := prec + 32
+	m := new(big.Float).SetPrec(p)
+	e := x.MantExp(m)
+	one := new(big.Float).SetPrec(p).SetInt64(1)
+	z := new(big.Float).SetPrec(p).Sub(m, one)
+	z.Quo(z, new(big.Float).SetPrec(p).Add(m, one))
+	l := bigAtanh2(z, p)
+	if e != 0 {
+		ln2 := bigAtanh2(new(big.Float).SetPrec(p).Quo(one, new(big.Float).SetInt64(3)), p)
+		l.Add(l, ln2.Mul(ln2, new(big.Float).SetInt64(int64(e))))
+	}
+	return l.SetPrec(prec)
+}
+
+// bigAtanh2 returns 2*atanh(z), abs(z) <= 1/3, by the series
+// 2 * (z + z^3/3 + z^5/5 + ...) to prec bits.
+func bigAtanh2(z *big.Float, prec uint) *big.Float {
+	sum := new(big.Float).SetPrec(prec).Set(z)
+	z2 := new(big.Float).SetPrec(prec).Mul(z, z)
+	t := new(big.Float).SetPrec(prec).Set(z)
+	term := new(big.Float).SetPrec(prec)
+	for k := int64(3); ; k += 2 {
+		t.Mul(t, z2)
+		term.Quo(t, new(big.Float).SetInt64(k))
+		if term.Sign() == 0 || term.MantExp(nil)-sum.MantExp(nil) < -int(prec) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return sum.Mul(sum, new(big.Float).SetInt64(2))
+}
+
+// powExactTie returns true if x^y == m exactly, for y = n/2^k with
+// k <= 5 and abs(n) <= 4096, by checking m^(2^k) == x^n exactly.
+// It returns false for other y.
+func powExactTie(x, y float64, m *big.Float) bool {
+	k := 0
+	for ; k <= 5 && y != math.Trunc(y); k++ {
+		y *= 2
+	}
+	if k > 5 || math.Abs(y) > 4096 {
+		return false
+	}
+	n := int(y)
+	an := n
+	if n < 0 {
+		an = -n
+	}
+	lhs := new(big.Float).SetPrec(m.MinPrec())
+	lhs.Set(m)
+	for i := 0; i < k; i++ {
+		lhs.SetPrec(2 * lhs.MinPrec()).Mul(lhs, lhs)            // exact squares
+	}
+	rhs := bigPowExact(new(big.Float).SetFloat64(x), an)
+	if n < 0 {
+		lhs.SetPrec(lhs.MinPrec() + rhs.MinPrec()).Mul(lhs, rhs)
+		return lhs.Cmp(big.NewFloat(1)) == 0
+	}
+	return lhs.Cmp(rhs) == 0
+}
+
+// bigPowExact returns x^n, n >= 0, exactly.
+func bigPowExact(x *big.Float, n int) *big.Float {
+	r := new(big.Float).SetInt64(1)
+	b := new(big.Float).Set(x)
+	for ; n > 0; n >>= 1 {
+		if n&1 != 0 {
+			r.SetPrec(r.MinPrec() + b.MinPrec()).Mul(r, b)
+		}
+		b.SetPrec(2 * b.MinPrec()).Mul(b, b)
+	}
+	return r
+}
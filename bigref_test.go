@@ -0,0 +1,641 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+func BenchmarkSubtractionUnderflowed(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is = SubtractionUnderflowed(0x1p-1020+float64(n)*0x1p-1074, 0x1p-1020)
+	}
+	bsink = is
+}
+
+func BenchmarkReciprocal(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y, _ = Reciprocal(float64(n + 3))
+	}
+	fsink = y
+}
+
+// The division path alone, without the error computation.
+func BenchmarkReciprocalDivision(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = 1 / float64(n+3)
+	}
+	fsink = y
+}
+
+// ------------------------------------------------------------- Tests
+func TestIsDecimalRepresentable(t *testing.T) {
+	cases := []struct {
+		num, den int64
+		want     bool
+	}{
+		{3, 8, true},
+		{-3, 8, true},
+		{1, 10, false},
+		{1, 1 << 60, true},
+		{6, 12, true},
+		{1, 3, false},
+		{0, 7, true},
+		{1, 0, false},
+		{1<<53 + 1, 1, false},
+		{1<<53 + 2, 1, true},
+		{1<<62 - 1, 1 << 62, false},
+		{-1 << 63, 1, true},
+	}
+	for _, c := range cases {
+		is := IsDecimalRepresentable(c.num, c.den)
+		t.Logf("%d/%d   %v", c.num, c.den, is)
+		if is != c.want {
+			t.Fatalf("IsDecimalRepresentable(%d, %d) = %v", c.num, c.den, is)
+		}
+	}
+}
+
+func TestExpectedRounded(t *testing.T) {
+	const rounds int = 1e5
+	zero, inf := 0.0, math.Inf(1)
+	t.Logf("0.1 + 0.2      %v", ExpectedRounded(0.1, 0.2, '+'))
+	t.Logf("1 / 3          %v", ExpectedRounded(1, 3, '/'))
+	t.Logf("1 / -0         %v", ExpectedRounded(1, -zero, '/'))
+	t.Logf("0 / 0          %v", ExpectedRounded(0, 0, '/'))
+	t.Logf("inf - inf      %v", ExpectedRounded(inf, inf, '-'))
+	t.Logf("1 - 1          %v", ExpectedRounded(1, 1, '-'))
+	t.Logf("-0 + -0        %v", ExpectedRounded(-zero, -zero, '+'))
+	t.Logf("max * 2        %v", ExpectedRounded(math.MaxFloat64, 2, '*'))
+	t.Logf("1 %% 2          %v", ExpectedRounded(1, 2, '%'))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		a := RandomFloat64(&state)
+		b := RandomFloat64(&state)
+		if i&1 == 0 {
+			b = math.Ldexp(b, Log2(a)-Log2(b)+int(Splitmix(&state)%120)-60)
+		}
+		for _, op := range []byte("+-*/") {
+			f := ieeeOp(a, b, op)
+			e := ExpectedRounded(a, b, op)
+			if math.Float64bits(f) != math.Float64bits(e) {
+				t.Logf("i    %d  %c", i, op)
+				t.Logf("a    %v", a)
+				t.Logf("b    %v", b)
+				t.Logf("hw   %v", f)
+				t.Fatalf("big  %v", e)
+			}
+		}
+	}
+}
+
+func TestSqrtIsCorrectlyRounded(t *testing.T) {
+	const rounds int = 1e5
+	zero, inf, nan := 0.0, math.Inf(1), math.NaN()
+	t.Logf("sqrt(2)        %v", SqrtIsCorrectlyRounded(2, math.Sqrt(2)))
+	t.Logf("sqrt(2)+ulp    %v", SqrtIsCorrectlyRounded(2, NextFromZero(math.Sqrt(2))))
+	t.Logf("sqrt(-0)       %v", SqrtIsCorrectlyRounded(-zero, -zero))
+	t.Logf("sqrt(-0) = 0   %v", SqrtIsCorrectlyRounded(-zero, zero))
+	t.Logf("sqrt(-1)       %v", SqrtIsCorrectlyRounded(-1, nan))
+	t.Logf("sqrt(inf)      %v", SqrtIsCorrectlyRounded(inf, inf))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := math.Abs(RandomFloat64(&state))
+		if i&3 == 0 {
+			x = math.Float64frombits(Splitmix(&state) & (1<<52 - 1))    // subnormal
+		}
+		if x == 0 {
+			continue
+		}
+		r := math.Sqrt(x)
+		if !SqrtIsCorrectlyRounded(x, r) ||
+			SqrtIsCorrectlyRounded(x, NextFromZero(r)) ||
+			SqrtIsCorrectlyRounded(x, NextToZero(r)) {
+			t.Logf("i    %d", i)
+			t.Logf("x    %v", x)
+			t.Fatalf("r    %v", r)
+		}
+	}
+}
+
+func TestRoundingModeUlpDelta(t *testing.T) {
+	third := new(big.Float).SetPrec(200).Quo(big.NewFloat(1), big.NewFloat(3))
+	tenth, _ := new(big.Float).SetPrec(200).SetString("0.1")
+	cases := []struct {
+		x                    *big.Float
+		toZero, toPos, toNeg uint64
+	}{
+		{third, 0, 1, 0},                                    // nearest 1/3 is below 1/3
+		{new(big.Float).Neg(third), 0, 0, 1},
+		{tenth, 1, 0, 1},                                    // nearest 0.1 is above 0.1
+		{new(big.Float).Neg(tenth), 1, 1, 0},
+		{big.NewFloat(0.1), 0, 0, 0},                        // exactly a float64
+		{big.NewFloat(math.Inf(-1)), 0, 0, 0},
+		{new(big.Float).SetMantExp(big.NewFloat(1), 1024), 1, 0, 1},   // +Inf, beyond MaxFloat64
+		{new(big.Float).SetMantExp(big.NewFloat(1), -1076), 0, 1, 0},  // 0, below 2^-1074
+	}
+	for _, c := range cases {
+		d := RoundingModeUlpDelta(c.x)
+		t.Logf("%-12.6g %v", c.x, d)
+		if d["ToZero"] != c.toZero || d["ToPositiveInf"] != c.toPos || d["ToNegativeInf"] != c.toNeg {
+			t.Fatalf("%v: %v", c.x, d)
+		}
+	}
+}
+
+func TestRsqrtError(t *testing.T) {
+	const rounds int = 1e5
+	zero, inf, nan := 0.0, math.Inf(1), math.NaN()
+	t.Logf("4, 0.5         %v", RsqrtError(4, 0.5))
+	t.Logf("2              %v", RsqrtError(2, 1/math.Sqrt(2)))
+	t.Logf("2 next         %v", RsqrtError(2, NextFromZero(1/math.Sqrt(2))))
+	t.Logf("+0, +Inf       %v", RsqrtError(zero, inf))
+	t.Logf("-0, +Inf       %v", RsqrtError(-zero, inf))
+	t.Logf("-1, NaN        %v", RsqrtError(-1, nan))
+	t.Logf("Inf, 0         %v", RsqrtError(inf, 0))
+	t.Logf("2, NaN         %v", RsqrtError(2, nan))
+	if RsqrtError(4, 0.5) != 0 || RsqrtError(zero, inf) != 0 || RsqrtError(-zero, -inf) != 0 ||
+		RsqrtError(-zero, inf) != inf || RsqrtError(-1, nan) != 0 || RsqrtError(inf, 0) != 0 {
+		t.Fatalf("special cases")
+	}
+	// Correctly rounded 1/sqrt(x), from 60 digit decimal arithmetic.
+	known := []struct{ x, r float64 }{
+		{2, 0x1.6a09e667f3bcdp-1},
+		{3, 0x1.279a74590331cp-1},
+		{5, 0x1.c9f25c5bfedd9p-2},
+		{10, 0x1.43d136248490fp-2},
+		{1e-300, 0x1.38d352e5096afp+498},
+		{7e300, 0x1.3cbacd0a16c50p-500},
+	}
+	for _, c := range known {
+		e := RsqrtError(c.x, c.r)
+		down, up := RsqrtError(c.x, NextToZero(c.r)), RsqrtError(c.x, NextFromZero(c.r))
+		t.Logf("%-8v %9.6f %9.6f %9.6f", c.x, down, e, up)
+		if !(math.Abs(e) < 0.5) || !(math.Abs(down) > 0.5) || !(math.Abs(up) > 0.5) {
+			t.Fatalf("x %v: errors %v %v %v", c.x, down, e, up)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := math.Abs(RandomFloat64(&state))
+		if x == 0 {
+			continue
+		}
+		r := ExpectedRounded(1, math.Sqrt(x), '/')        // not always correctly rounded
+		e := RsqrtError(x, r)
+		up, down := RsqrtError(x, NextFromZero(r)), RsqrtError(x, NextToZero(r))
+		// The correctly rounded value is the neighbour with abs(error) < 0.5.
+		var best float64
+		switch {
+		case math.Abs(e) < 0.5:
+			best = e
+		case math.Abs(up) < 0.5:
+			best = up
+		case math.Abs(down) < 0.5:
+			best = down
+		default:
+			t.Logf("i    %d", i)
+			t.Logf("x    %v", x)
+			t.Fatalf("errors %v %v %v", down, e, up)
+		}
+		if math.Abs(e) > 1.5 {
+			t.Fatalf("x %v: 1/sqrt(x) error %v, best %v", x, e, best)
+		}
+		// Only one of the three is within half an ulp. At a power of two
+		// the float below is half an ulp away.
+		within := 0
+		for _, d := range []float64{down, e, up} {
+			if math.Abs(d) < 0.5 {
+				within++
+			}
+		}
+		if within != 1 {
+			t.Fatalf("x %v: errors %v %v %v", x, down, e, up)
+		}
+	}
+}
+
+func TestUlpsBetweenBig(t *testing.T) {
+	const rounds int = 1e5
+	inf := math.Inf(1)
+	t.Logf("-Inf, +Inf     %v", UlpsBetweenBig(-inf, inf))
+	t.Logf("-1, 1          %v", UlpsBetweenBig(-1, 1))
+	if UlpsBetweenBig(-inf, inf).Uint64() != UlpsBetween(-inf, inf) ||
+		UlpsBetweenBig(math.Copysign(0, -1), 0).Sign() != 0 ||
+		UlpsBetweenBig(1, math.NaN()) != nil {
+		t.Fatalf("special cases")
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		var y float64
+		switch i & 3 {
+		case 0:
+			y = RandomFloat64(&state)
+		case 1:
+			y = x + math.Ldexp(RandomFloat64(&state), Log2(x)-Log2(RandomFloat64(&state)))
+		case 2:
+			y = math.Float64frombits(math.Float64bits(x) ^ Splitmix(&state)>>(12+Splitmix(&state)%52))
+		case 3:
+			y = -x
+		}
+		if y != y {
+			continue
+		}
+		b := UlpsBetweenBig(x, y)
+		if !b.IsUint64() || b.Uint64() != UlpsBetween(x, y) {
+			t.Logf("x    %v", x)
+			t.Logf("y    %v", y)
+			t.Fatalf("big  %v, UlpsBetween %d", b, UlpsBetween(x, y))
+		}
+	}
+}
+
+func TestWasRounded(t *testing.T) {
+	const rounds int = 1e4
+	inf, nan := math.Inf(1), math.NaN()
+	t.Logf("0.1 + 0.2      %v", WasRounded(0.1, 0.2, 0.1+0.2, '+'))
+	t.Logf("0.5 + 0.25     %v", WasRounded(0.5, 0.25, 0.5+0.25, '+'))
+	t.Logf("1 / 3          %v", WasRounded(1, 3, 1.0/3, '/'))
+	t.Logf("max * 2        %v", WasRounded(math.MaxFloat64, 2, inf, '*'))
+	t.Logf("1 / 0          %v", WasRounded(1, 0, inf, '/'))
+	t.Logf("inf - inf      %v", WasRounded(inf, inf, nan, '-'))
+	if !WasRounded(0.1, 0.2, 0.1+0.2, '+') || WasRounded(0.5, 0.25, 0.75, '+') ||
+		!WasRounded(math.MaxFloat64, 2, inf, '*') || WasRounded(1, 0, inf, '/') ||
+		WasRounded(inf, inf, nan, '-') || !WasRounded(1, 0, -inf, '/') ||
+		!WasRounded(1, 2, 3, '%') || !WasRounded(nan, 2, nan, '%') ||
+		WasRounded(nan, 2, nan, '*') || WasRounded(1, nan, nan, '-') {
+		t.Fatalf("special cases")
+	}
+	state := uint64(1)
+	exact, inexact := 0, 0
+	for i := 0; i < rounds; i++ {
+		a := RandomFloat64(&state)
+		b := RandomFloat64(&state)
+		if i&1 == 0 {
+			a = float64(int32(Splitmix(&state)))
+			b = float64(int32(Splitmix(&state)))
+		}
+		for _, op := range []byte("+-*/") {
+			f := ieeeOp(a, b, op)
+			// The error-free residual of the operation is zero if and only
+			// if f is exact. For * and / the residual from math.FMA is
+			// exact if it doesn't underflow, abs(a*b) and abs(a) >= 2^-968.
+			var res float64
+			switch op {
+			case '+', '-':
+				c := b
+				if op == '-' {
+					c = -b
+				}
+				cc := f - a                           // TwoSum(a, c)
+				res = (a - (f - cc)) + (c - cc)
+			case '*':
+				res = math.FMA(a, b, -f)
+				if math.Abs(f) < 0x1p-968 {
+					continue
+				}
+			case '/':
+				res = math.FMA(-f, b, a)
+				if math.Abs(a) < 0x1p-968 || f == 0 {
+					continue
+				}
+			}
+			want := !IsFinite(f) || res != 0
+			if want {
+				inexact++
+			} else {
+				exact++
+			}
+			if IsFinite(a) && IsFinite(b) && !(op == '/' && b == 0) &&
+				WasRounded(a, b, f, op) != want || !WasRounded(a, b, NextFromZero(f), op) && f != 0 {
+				t.Logf("i    %d  %c", i, op)
+				t.Logf("a    %v", a)
+				t.Fatalf("b    %v", b)
+			}
+		}
+	}
+	t.Logf("exact %d  inexact %d", exact, inexact)
+	if exact < rounds/2 || inexact < rounds/2 {
+		t.Fatalf("exact %d, inexact %d", exact, inexact)
+	}
+}
+
+func TestParseFloatDirected(t *testing.T) {
+	const rounds int = 2e4
+	modes := []big.RoundingMode{big.ToNearestEven, big.ToNearestAway, big.ToZero,
+		big.AwayFromZero, big.ToNegativeInf, big.ToPositiveInf}
+	down, _ := ParseFloatDirected("0.1", big.ToNegativeInf)
+	up, _ := ParseFloatDirected("0.1", big.ToPositiveInf)
+	t.Logf("0.1 down       %v", down)
+	t.Logf("0.1 up         %v", up)
+	if down != NextToZero(0.1) || up != 0.1 || !Adjacent(down, up) {   // nearest 0.1 is above 0.1
+		t.Fatalf("0.1: %v %v", down, up)
+	}
+	down, _ = ParseFloatDirected("-0.1", big.ToZero)
+	up, _ = ParseFloatDirected("-0.1", big.AwayFromZero)
+	if down != -NextToZero(0.1) || up != -0.1 {
+		t.Fatalf("-0.1: %v %v", down, up)
+	}
+	cases := []struct {
+		s    string
+		mode big.RoundingMode
+		want float64
+		err  bool
+	}{
+		{"9007199254740993", big.ToNearestEven, 0x1p53, false},      // 2^53 + 1, a tie
+		{"9007199254740993", big.ToNearestAway, 0x1p53 + 2, false},
+		{"-9007199254740993", big.ToNearestAway, -0x1p53 - 2, false},
+		{"0.5", big.ToZero, 0.5, false},
+		{"1e400", big.ToZero, math.MaxFloat64, false},
+		{"-1e400", big.ToPositiveInf, -math.MaxFloat64, false},
+		{"1e400", big.ToNearestEven, math.Inf(1), true},
+		{"1e400000000", big.ToZero, math.MaxFloat64, false},
+		{"1e-400", big.AwayFromZero, 0x1p-1074, false},
+		{"1e-400", big.ToPositiveInf, 0x1p-1074, false},
+		{"1e-400", big.ToNegativeInf, 0, false},
+		{"-1e-400", big.ToNegativeInf, -0x1p-1074, false},
+		{"-1e-400000000", big.AwayFromZero, -0x1p-1074, false},
+		{"3e-324", big.ToZero, 0, false},
+		{"-0", big.AwayFromZero, math.Copysign(0, -1), false},
+		{"0x1.8p-1074", big.ToNearestAway, 0x1p-1073, false},
+		{"-Inf", big.ToZero, math.Inf(-1), false},
+		{"0.1x", big.ToZero, 0, true},
+		{"0x1.0000_0000_0000_08p0", big.ToNearestAway, 1 + 0x1p-52, false},
+		{"0x_1.0000_0000_0000_01p-1_0", big.AwayFromZero, 0x1p-10 + 0x1p-62, false},
+		{"0x_1.0000_0000_0000_01p-1_0", big.ToZero, 0x1p-10, false},
+		{"1_000.000_000_000_000_000_1", big.ToPositiveInf, 1000 + 0x1p-43, false},
+		{"1__0", big.ToZero, 0, true},
+	}
+	for _, c := range cases {
+		f, err := ParseFloatDirected(c.s, c.mode)
+		t.Logf("%-18s %-14v %-24v %v", c.s, c.mode, f, err)
+		if math.Float64bits(f) != math.Float64bits(c.want) || (err != nil) != c.err {
+			t.Fatalf("ParseFloatDirected(%q, %v) = %v, %v", c.s, c.mode, f, err)
+		}
+	}
+	if f, _ := ParseFloatDirected("nan", big.ToZero); f == f {
+		t.Fatalf("nan")
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		if math.Abs(x) < 0x1p-1000 {
+			continue
+		}
+		s := strconv.FormatFloat(x, 'e', int(Splitmix(&state)%25), 64)
+		for _, mode := range modes {
+			f, err := ParseFloatDirected(s, mode)
+			b, _, _ := new(big.Float).SetPrec(53).SetMode(mode).Parse(s, 10)
+			want, _ := b.Float64()
+			if f != want && !IsInf(want) || IsInf(f) != (err != nil) {    // big.Float.Float64 overflows to Inf
+				t.Logf("s    %s %v", s, mode)
+				t.Fatalf("f    %v, want %v", f, want)
+			}
+		}
+	}
+}
+
+func TestSubtractionUnderflowed(t *testing.T) {
+	const rounds int = 1e5
+	x, y := 0x1.0000000000001p-1022, 0x1p-1022
+	t.Logf("%v - %v = %v  %v", x, y, x-y, SubtractionUnderflowed(x, y))
+	if d := x - y; Classify(d) != Subnormal || d != 0x1p-1074 || SubtractionUnderflowed(x, y) {
+		t.Fatalf("%v - %v = %v", x, y, d)
+	}
+	// Hauser: subnormal differences are exact with gradual underflow.
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := math.Float64frombits(Splitmix(&state)>>10 | Splitmix(&state)&signbit)    // exponent 0 - 3
+		y := NextToward(x, 0)
+		if i&1 == 0 {
+			y = math.Float64frombits(math.Float64bits(x) ^ Splitmix(&state)>>12)
+		}
+		d := x - y
+		if math.Abs(d) < 0x1p-1022 && SubtractionUnderflowed(x, y) {
+			t.Logf("x    %v", x)
+			t.Logf("y    %v", y)
+			t.Fatalf("d    %v", d)
+		}
+	}
+	if SubtractionUnderflowed(1, 0.5) || SubtractionUnderflowed(math.Inf(1), math.Inf(1)) ||
+		SubtractionUnderflowed(math.NaN(), 0) {
+		t.Fatalf("special cases")
+	}
+}
+
+func TestReciprocal(t *testing.T) {
+	const rounds int = 1e5
+	zero, inf := 0.0, math.Inf(1)
+	for _, x := range []float64{3, 10, 0x1p-1000, 0x1p1023, 0x1p1020 * 3, 0x1p-1030, 0x1p-1025} {
+		r, e := Reciprocal(x)
+		t.Logf("1/%-22v %-24v %v ulps", x, r, e)
+	}
+	if r, e := Reciprocal(3); r != 1.0/3 || !(math.Abs(e) < 0.5) || e == 0 {
+		t.Fatalf("1/3: %v %v", r, e)
+	}
+	for exp := -1023; exp <= 1023; exp++ {
+		x := math.Ldexp(1, exp)
+		if r, e := Reciprocal(-x); e != 0 || r != -math.Ldexp(1, -exp) {
+			t.Fatalf("1/2^%d: %v %v", exp, r, e)
+		}
+	}
+	if r, e := Reciprocal(-zero); r != -inf || e != 0 {
+		t.Fatalf("1/-0: %v %v", r, e)
+	}
+	if r, e := Reciprocal(inf); r != 0 || e != 0 {
+		t.Fatalf("1/Inf: %v %v", r, e)
+	}
+	if r, e := Reciprocal(math.NaN()); r == r || e == e {
+		t.Fatalf("1/NaN: %v %v", r, e)
+	}
+	if r, e := Reciprocal(-0x1p-1024); r != -inf || e != -inf {
+		t.Fatalf("1/-2^-1024: %v %v", r, e)
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		r, e := Reciprocal(x)
+		if IsFinite(r) && !(math.Abs(e) <= 0.5) {
+			t.Logf("x    %v", x)
+			t.Fatalf("r    %v  %v ulps", r, e)
+		}
+	}
+}
+
+func TestRandomOpTriple(t *testing.T) {
+	const rounds int = 2e4
+	state := uint64(1)
+	specialA, specialB := 0, 0
+	for _, op := range []byte{'+', '-', '*', '/'} {
+		specials, rounded := 0, 0
+		for i := 0; i < rounds; i++ {
+			a, b, r := RandomOpTriple(&state, op)
+			if !IsFinite(a) || !IsFinite(b) {
+				specials++
+			}
+			if !IsFinite(a) {
+				specialA++
+			}
+			if !IsFinite(b) {
+				specialB++
+			}
+			if WasRounded(a, b, r, op) {
+				rounded++
+			}
+			h := ieeeOp(a, b, op)
+			if math.Float64bits(h) != math.Float64bits(r) && !(h != h && r != r) {
+				t.Fatalf("%v %c %v = %v, RandomOpTriple result %v", a, op, b, h, r)
+			}
+		}
+		t.Logf("%c  specials %d  rounded %d", op, specials, rounded)
+	}
+	// Each operand is Inf or NaN with probability 1/2048, ~39 of 4 * rounds.
+	t.Logf("Inf/NaN  a %d  b %d  of %d", specialA, specialB, 4*rounds)
+	if specialA < 15 || specialA > 70 || specialB < 15 || specialB > 70 {
+		t.Fatalf("Inf/NaN operands: a %d, b %d of %d", specialA, specialB, 4*rounds)
+	}
+	if a, b, r := RandomOpTriple(&state, '%'); a == a || b == b || r == r {
+		t.Fatalf("unknown op: %v %v %v", a, b, r)
+	}
+}
+
+func TestCbrtIsCorrectlyRounded(t *testing.T) {
+	const rounds int = 1e5
+	zero, inf, nan := 0.0, math.Inf(1), math.NaN()
+	cases := []struct {
+		x, r float64
+		want bool
+	}{
+		{8, 2, true},
+		{-27, -3, true},
+		{-27, 3, false},
+		{8, NextFromZero(2), false},
+		{-zero, -zero, true},
+		{-zero, zero, false},
+		{-inf, -inf, true},
+		{nan, nan, true},
+		{nan, 1, false},
+		{0x1p-1074, 0x1p-358, true},
+		{math.MaxFloat64, math.Cbrt(math.MaxFloat64), true},
+	}
+	for _, c := range cases {
+		is := CbrtIsCorrectlyRounded(c.x, c.r)
+		t.Logf("%-24v %-24v %v", c.x, c.r, is)
+		if is != c.want {
+			t.Fatalf("CbrtIsCorrectlyRounded(%v, %v) = %v", c.x, c.r, is)
+		}
+	}
+	state := uint64(1)
+	misrounded := 0
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		if x == 0 {
+			continue
+		}
+		r := math.Cbrt(x)
+		if !CbrtIsCorrectlyRounded(x, r) {
+			misrounded++                          // math.Cbrt error is < 0.667 ulps
+			if r = NextFromZero(r); !CbrtIsCorrectlyRounded(x, r) {
+				r = NextToZero(NextToZero(r))
+			}
+		}
+		if !CbrtIsCorrectlyRounded(x, r) ||
+			CbrtIsCorrectlyRounded(x, NextFromZero(r)) ||
+			CbrtIsCorrectlyRounded(x, NextToZero(r)) {
+			t.Logf("i    %d", i)
+			t.Logf("x    %v", x)
+			t.Fatalf("r    %v", r)
+		}
+	}
+	// About 8 % of math.Cbrt results are the other neighbour of the cube root.
+	t.Logf("math.Cbrt misrounded %d of %d", misrounded, rounds)
+}
+
+func TestPowIsCorrectlyRounded(t *testing.T) {
+	const rounds int = 300
+	inf, nan, nz := math.Inf(1), math.NaN(), math.Copysign(0, -1)
+	// The special cases of the math.Pow contract.
+	xs := []float64{0, nz, 1, -1, 0.5, -0.5, 2, -2, 3, -3, inf, -inf, nan}
+	ys := []float64{0, nz, 1, -1, 2, -2, 3, -3, 0.5, -0.5, inf, -inf, nan, 0x1p60}
+	for _, x := range xs {
+		for _, y := range ys {
+			if _, ok := powSpecial(x, y); ok && !PowIsCorrectlyRounded(x, y, math.Pow(x, y)) {
+				t.Fatalf("Pow(%v, %v) = %v", x, y, math.Pow(x, y))
+			}
+		}
+	}
+	tt := float64(1<<18 - 1)                    // tt^3 is a midpoint, a tie
+	cases := []struct {
+		x, y, r float64
+		want    bool
+	}{
+		{2, 10, 1024, true},
+		{-2, 3, -8, true},
+		{-2, 3, 8, false},
+		{-2, 2, 4, true},
+		{-2, 0.5, nan, true},
+		{nz, -3, -inf, true},
+		{nz, -3, inf, false},
+		{2, 0.5, math.Sqrt2, true},
+		{2, 0.5, NextFromZero(math.Sqrt2), false},
+		{10, -1, 0.1, true},
+		{9, 1.5, 27, true},
+		{tt * tt, 1.5, 18014192351838208, true},        // the even neighbour
+		{tt * tt, 1.5, 18014192351838206, false},
+		{2, 1024, inf, true},
+		{2, 1023, inf, false},
+		{-2, -1075, nz, true},
+		{2, -1074, 0x1p-1074, true},
+		{2, -1074, 0, false},
+		{0.5, 1074.5, 0x1p-1074, true},
+		{0.5, 1075.5, 0, true},
+	}
+	for _, c := range cases {
+		is := PowIsCorrectlyRounded(c.x, c.y, c.r)
+		t.Logf("%-14v %-8v %-24v %v", c.x, c.y, c.r, is)
+		if is != c.want {
+			t.Fatalf("PowIsCorrectlyRounded(%v, %v, %v) = %v", c.x, c.y, c.r, is)
+		}
+	}
+	// math.Pow can be off by a few ulps, for integer y and large results by
+	// more. The correctly rounded result is searched near it, or near the
+	// big.Float power for integer y, and it must be the only one passing.
+	state := uint64(1)
+	misrounded := 0
+	for i := 0; i < rounds; i++ {
+		x := math.Ldexp(float64(Splitmix(&state)>>11)*0x1p-53+0.5, int(Splitmix(&state)%8)-4)
+		y := (float64(Splitmix(&state)>>11)*0x1p-53 - 0.5) * 20
+		if i%4 == 0 {
+			x, y = -x, math.Trunc(y)
+		}
+		p := math.Pow(x, y)
+		c := p
+		if y == math.Trunc(y) {
+			b := bigPowExact(new(big.Float).SetFloat64(x), int(math.Abs(y)))
+			if y < 0 {
+				b.Quo(new(big.Float).SetPrec(b.MinPrec()+64).SetInt64(1), b)
+			}
+			c, _ = b.Float64()
+		}
+		correct := 0
+		r := c
+		for k := int64(-8); k <= 8; k++ {
+			if q := fromOrdinal(ordinal(c) + k); PowIsCorrectlyRounded(x, y, q) {
+				correct++
+				r = q
+			}
+		}
+		if correct != 1 {
+			t.Fatalf("Pow(%v, %v) = %v: %d correctly rounded neighbours", x, y, p, correct)
+		}
+		if r != p {
+			misrounded++
+		}
+	}
+	t.Logf("math.Pow misrounded %d of %d", misrounded, rounds)
+}
@@ -0,0 +1,157 @@
+package fbits
+
+import (
+	"math"
+)
+
+// FloatsWithLog2 returns an iterator over all positive float64's x with
+// Log2(x) == exp, ie. 2^exp <= x < 2^(exp+1), in increasing order.
+//
+// A normal binade, -1022 <= exp <= 1023, has 2^52 floats, which is too many
+// to go through in a test. The subnormal binades, -1074 <= exp <= -1023,
+// have 2^(exp+1074) floats, 1 for exp = -1074. For other exp's the
+// iterator yields nothing. Negate the values for the negative binade.
+//
+//	for x := range FloatsWithLog2(-1060) { ... }
+//
+func FloatsWithLog2(exp int) func(yield func(float64) bool) {
+	return func(yield func(float64) bool) {
+		if exp < -1074 || exp > 1023 {
+			return
+		}
+		end := binadeBits(exp + 1)
+		for u := binadeBits(exp); u < end; u++ {
+			if !yield(math.Float64frombits(u)) {
+				return
+			}
+		}
+	}
+}
+
+// UlpGridIterator returns an iterator over lo and the floats after it,
+// strideUlps ulps apart, up to hi.
+//
+// The values are lo, lo + stride ulps, lo + 2*stride ulps, ... <= hi,
+// stepping in the order of the float64 values, also across zero and
+// subnormals. hi is yielded only if it is on the grid. The first value
+// is lo itself, a later zero is +0.
+// Special cases:
+// lo > hi               yields nothing
+// strideUlps == 0       yields lo once
+// lo or hi NaN          yields nothing
+//
+//	for x := range UlpGridIterator(-1, 1, 1<<50) { ... }
+//
+func UlpGridIterator(lo, hi float64, strideUlps uint64) func(yield func(float64) bool) {
+	return func(yield func(float64) bool) {
+		k, end := ordinal(lo), ordinal(hi)
+		if lo != lo || hi != hi || k > end {
+			return
+		}
+		if !yield(lo) || strideUlps == 0 {
+			return
+		}
+		for uint64(end-k) >= strideUlps {
+			k += int64(strideUlps)
+			if !yield(fromOrdinal(k)) {
+				return
+			}
+		}
+	}
+}
+
+// FirstFloatOfBinade returns 2^exp, the smallest float64 x with Log2(x) == exp.
+//
+// The float is constructed from the bits, exactly also for the
+// subnormal binades -1074 <= exp <= -1023.
+// Special cases:
+// FirstFloatOfBinade(exp < -1074)  = 0
+// FirstFloatOfBinade(exp > 1023)   = +Inf
+//
+func FirstFloatOfBinade(exp int) float64 {
+	switch {
+	case exp < -1074:
+		return 0
+	case exp > 1023:
+		return math.Inf(1)
+	}
+	return math.Float64frombits(binadeBits(exp))
+}
+
+// LastFloatOfBinade returns the largest float64 below 2^(exp+1), the largest
+// float64 x with Log2(x) == exp.
+//
+// LastFloatOfBinade(exp) and FirstFloatOfBinade(exp+1) are adjacent.
+// For the top binade exp = 1023 it is MaxFloat64 and for the
+// subnormal binade exp = -1074 it is 2^-1074, the only float in the binade.
+// Special cases:
+// LastFloatOfBinade(exp < -1074)  = 0
+// LastFloatOfBinade(exp > 1023)   = +Inf
+//
+func LastFloatOfBinade(exp int) float64 {
+	switch {
+	case exp < -1074:
+		return 0
+	case exp > 1023:
+		return math.Inf(1)
+	}
+	return math.Float64frombits(binadeBits(exp+1) - 1)
+}
+
+// UlpAtExp returns the ulp of the floats x with Log2(x) == exp,
+// 2^(exp-52) for the normal binades and 2^-1074 for the subnormal binades.
+//
+// UlpAtExp(Log2(x)) == Ulp(x) for all finite nonzero x. The spacing of
+// the floats in [2^exp, 2^(exp+1)) is UlpAtExp(exp) without a value from
+// the binade. The ulp is constructed from the bits.
+// Special cases:
+// UlpAtExp(exp <= -1022)  = 2^-1074
+// UlpAtExp(1023)          = 2^971
+// UlpAtExp(exp > 1023)    = +Inf    as Ulp(+/-Inf)
+//
+func UlpAtExp(exp int) float64 {
+	switch {
+	case exp < -1022:
+		exp = -1022
+	case exp > 1023:
+		return math.Inf(1)
+	}
+	return math.Float64frombits(binadeBits(exp - 52))
+}
+
+// PermutedBinadeInputs returns hi-lo+1 floats, one random float from each
+// binade lo <= exp <= hi, with a random sign and significand, in a random
+// order. It is a small test set over the magnitudes, reproducible from state.
+//
+// The float of binade exp has Log2(x) == exp and it is uniform in the
+// binade. The order is a Fisher-Yates shuffle driven by Splitmix.
+// lo and hi are clamped into [-1074, 1023], the binades of the nonzero
+// finite floats. PermutedBinadeInputs returns nil if lo > hi.
+//
+func PermutedBinadeInputs(state *uint64, lo, hi int) []float64 {
+	lo, hi = max(lo, -1074), min(hi, 1023)
+	if lo > hi {
+		return nil
+	}
+	s := make([]float64, 0, hi-lo+1)
+	for exp := lo; exp <= hi; exp++ {
+		first := binadeBits(exp)
+		n := binadeBits(exp+1) - first          // a power of two
+		u := Splitmix(state)
+		s = append(s, math.Float64frombits(first + u&(n-1) | u&signbit))
+	}
+	for i := len(s) - 1; i > 0; i-- {
+		j := Splitmix(state) % uint64(i+1)
+		s[i], s[j] = s[j], s[i]
+	}
+	return s
+}
+
+// binadeBits returns the bits of 2^exp, -1074 <= exp <= 1024.
+// binadeBits(1024) is the bits of +Inf.
+func binadeBits(exp int) uint64 {
+	if exp >= -1022 {
+		return uint64(exp + 1023) << 52
+	}
+	return 1 << uint(exp + 1074)
+}
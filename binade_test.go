@@ -0,0 +1,203 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+func BenchmarkUlpAtExp(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = UlpAtExp(n&2047 - 1074)
+	}
+	fsink = y
+}
+
+func BenchmarkUlpGridIterator(b *testing.B) {
+	var y float64
+	for x := range UlpGridIterator(-1, math.Inf(1), (1<<62)/uint64(b.N+1)) {
+		y = x
+	}
+	fsink = y
+}
+
+// ------------------------------------------------------------- Tests
+func TestFloatsWithLog2(t *testing.T) {
+	for exp := -1074; exp <= -1052; exp++ {
+		first, last, n := -1.0, -1.0, 0
+		for x := range FloatsWithLog2(exp) {
+			if n == 0 {
+				first = x
+			}
+			if Log2(x) != exp {
+				t.Fatalf("exp %d: Log2(%v) = %d", exp, x, Log2(x))
+			}
+			last = x
+			n++
+		}
+		if first != math.Ldexp(1, exp) || last != NextToZero(math.Ldexp(1, exp+1)) || n != 1<<(exp+1074) {
+			t.Fatalf("exp %d: first %v, last %v, n %d", exp, first, last, n)
+		}
+	}
+	for _, exp := range []int{-1022, -1, 0, 52, 1023} {
+		n := 0
+		for x := range FloatsWithLog2(exp) {
+			if n == 0 && x != math.Ldexp(1, exp) || Log2(x) != exp {
+				t.Fatalf("exp %d: n %d, x %v", exp, n, x)
+			}
+			if n++; n == 1000 {
+				break
+			}
+		}
+	}
+	for _, exp := range []int{-1075, 1024} {
+		for x := range FloatsWithLog2(exp) {
+			t.Fatalf("exp %d yielded %v", exp, x)
+		}
+	}
+}
+
+func TestFirstLastFloatOfBinade(t *testing.T) {
+	t.Logf("First(-1074)  %v", FirstFloatOfBinade(-1074))
+	t.Logf("Last(-1023)   %v", LastFloatOfBinade(-1023))
+	t.Logf("First(-1022)  %v", FirstFloatOfBinade(-1022))
+	t.Logf("Last(1023)    %v", LastFloatOfBinade(1023))
+	t.Logf("First(1024)   %v", FirstFloatOfBinade(1024))
+	for e := -1076; e <= 1025; e++ {
+		first, last := FirstFloatOfBinade(e), LastFloatOfBinade(e)
+		if e >= -1074 && e <= 1023 {
+			if first != math.Ldexp(1, e) || Log2(first) != e || Log2(last) != e {
+				t.Fatalf("exp %d: first %v, last %v", e, first, last)
+			}
+		}
+		if e >= -1075 && e < 1024 && !(UlpsBetween(last, FirstFloatOfBinade(e+1)) == 1 && Adjacent(last, FirstFloatOfBinade(e+1))) {
+			t.Fatalf("exp %d: last %v and next first %v not adjacent", e, last, FirstFloatOfBinade(e+1))
+		}
+	}
+	if LastFloatOfBinade(1023) != math.MaxFloat64 || LastFloatOfBinade(-1074) != 0x1p-1074 ||
+		LastFloatOfBinade(-1023) != 0x1p-1022-0x1p-1074 || FirstFloatOfBinade(-1075) != 0 {
+		t.Fatalf("edge cases")
+	}
+}
+
+func TestUlpAtExp(t *testing.T) {
+	const rounds int = 1e7
+	for _, e := range []int{-2000, -1075, -1074, -1023, -1022, -1021, -971, 0, 52, 1023, 1024} {
+		t.Logf("%5d  %v", e, UlpAtExp(e))
+	}
+	if UlpAtExp(-1075) != 0x1p-1074 || UlpAtExp(-1022) != 0x1p-1074 || UlpAtExp(-1021) != 0x1p-1073 ||
+		UlpAtExp(0) != 0x1p-52 || UlpAtExp(1023) != 0x1p971 || !IsInf(UlpAtExp(1024)) {
+		t.Fatalf("special cases")
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		if i&7 == 0 {
+			x = math.Float64frombits(Splitmix(&state) >> 12)    // subnormal
+		}
+		if x == 0 {
+			continue
+		}
+		if UlpAtExp(Log2(x)) != Ulp(x) {
+			t.Logf("x    %v", x)
+			t.Fatalf("UlpAtExp(%d) = %v, Ulp %v", Log2(x), UlpAtExp(Log2(x)), Ulp(x))
+		}
+	}
+}
+
+func TestUlpGridIterator(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		lo, hi float64
+		stride uint64
+	}{
+		{1, 2, 1 << 48},
+		{-1, 1, 1 << 58},
+		{-inf, inf, 1<<63 + 12345},
+		{-inf, inf, maxUint64},
+		{-0x1p-1070, 0x1p-1070, 3},
+		{math.MaxFloat64, inf, 1},
+		{1, 1, 5},
+	}
+	for _, c := range cases {
+		var g []float64
+		for x := range UlpGridIterator(c.lo, c.hi, c.stride) {
+			g = append(g, x)
+		}
+		if len(g) == 0 || g[0] != c.lo || UlpsBetween(g[len(g)-1], c.hi) >= c.stride {
+			t.Fatalf("%v %v %d: %v", c.lo, c.hi, c.stride, g)
+		}
+		for i := 1; i < len(g); i++ {
+			if UlpsBetween(g[i-1], g[i]) != c.stride || g[i] > c.hi || !(g[i-1] < g[i]) {
+				t.Fatalf("%v %v %d: %v, %v", c.lo, c.hi, c.stride, g[i-1], g[i])
+			}
+		}
+		t.Logf("%v %v %d: %d values", c.lo, c.hi, c.stride, len(g))
+	}
+	count := func(lo, hi float64, stride uint64) (n int) {
+		for range UlpGridIterator(lo, hi, stride) {
+			n++
+		}
+		return
+	}
+	if count(2, 1, 1) != 0 || count(1, 2, 0) != 1 || count(math.NaN(), 1, 1) != 0 ||
+		count(-0x1p-1073, 0x1p-1073, 1) != 5 || count(-inf, inf, maxUint64) != 1 {
+		t.Fatalf("special cases")
+	}
+	n := 0
+	for range UlpGridIterator(0, 1, 1) {
+		if n++; n == 10 {
+			break
+		}
+	}
+}
+
+func TestPermutedBinadeInputs(t *testing.T) {
+	ranges := []struct{ lo, hi, n int }{
+		{-1074, 1023, 2098},
+		{-3, 3, 7},
+		{5, 5, 1},
+		{-2000, -1070, 5},
+		{1020, 2000, 4},
+		{3, 2, 0},
+	}
+	for _, r := range ranges {
+		state := uint64(42)
+		s := PermutedBinadeInputs(&state, r.lo, r.hi)
+		if len(s) != r.n {
+			t.Fatalf("[%d, %d]: %d floats, want %d", r.lo, r.hi, len(s), r.n)
+		}
+		seen := map[int]bool{}
+		for _, x := range s {
+			e := Log2(x)
+			if seen[e] || e < r.lo || e > r.hi || !IsFinite(x) {
+				t.Fatalf("[%d, %d]: %v, Log2 %d", r.lo, r.hi, x, e)
+			}
+			seen[e] = true
+		}
+		state = 42
+		s2 := PermutedBinadeInputs(&state, r.lo, r.hi)
+		for i := range s {
+			if math.Float64bits(s[i]) != math.Float64bits(s2[i]) {
+				t.Fatalf("[%d, %d]: not reproducible at %d", r.lo, r.hi, i)
+			}
+		}
+	}
+	state := uint64(1)
+	a := PermutedBinadeInputs(&state, -10, 10)
+	b := PermutedBinadeInputs(&state, -10, 10)
+	same, neg := 0, 0
+	for i := range a {
+		if Log2(a[i]) == Log2(b[i]) {
+			same++
+		}
+		if a[i] < 0 {
+			neg++
+		}
+	}
+	t.Logf("%v", a[:5])
+	t.Logf("same position %d  negative %d of %d", same, neg, len(a))
+	if same == len(a) || neg == 0 || neg == len(a) {
+		t.Fatalf("not random: same %d  negative %d", same, neg)
+	}
+}
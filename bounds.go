@@ -0,0 +1,82 @@
+package fbits
+
+import (
+	"math"
+)
+
+// unitRoundoff is the relative rounding error bound of float64, 2^-53.
+const unitRoundoff = 0x1p-53
+
+// ErrorBoundAfterOps returns the relative error bound (1+u)^n - 1 of a result
+// of n successive operations, each with a relative rounding error at most u.
+//
+// u is relError, or the float64 unit roundoff 2^-53 if relError <= 0.
+// (1+u)^n - 1 is computed as Expm1(n * Log1p(u)), which is accurate also
+// when n*u is small. For n*u << 1 the bound is ~n*u.
+// Special cases:
+// ErrorBoundAfterOps(n <= 0, u) = 0
+// ErrorBoundAfterOps(n, NaN)    = NaN
+//
+func ErrorBoundAfterOps(n int, relError float64) float64 {
+	u := relError
+	if u <= 0 {
+		u = unitRoundoff
+	}
+	if n <= 0 {
+		return 0
+	}
+	return math.Expm1(float64(n) * math.Log1p(u))
+}
+
+// GammaBound returns Higham's bound gamma_n = n*u / (1 - n*u) for the relative
+// error of n operations, each with a relative rounding error at most u.
+//
+// u is relError, or 2^-53 if relError <= 0. gamma_n is an upper bound of
+// (1+u)^n - 1 and it is valid only for n*u < 1. For n*u >= 1 GammaBound
+// returns +Inf. For n*u <= 0.01, gamma_n < 1.0102 * n*u.
+// Special cases:
+// GammaBound(n <= 0, u)  = 0
+// GammaBound(n, NaN)     = NaN
+//
+func GammaBound(n int, relError float64) float64 {
+	u := relError
+	if u <= 0 {
+		u = unitRoundoff
+	}
+	if n <= 0 {
+		return 0
+	}
+	nu := float64(n) * u
+	if nu >= 1 {
+		return math.Inf(1)
+	}
+	return nu / (1 - nu)
+}
+
+// ExpectedUlpError returns an ulp tolerance for the result of ops
+// operations with the condition number condNumber,
+// ceil(condNumber * gamma_ops / 2^-53).
+//
+// The model is the standard forward error bound: with backward error at
+// most gamma_ops = GammaBound(ops, 0), the relative error of the result is
+// at most condNumber * gamma_ops. A relative error e is at most e / 2^-53
+// ulps, since an ulp is 2^-53 to 2^-52 times the value, so the tolerance
+// is a little over condNumber*ops ulps for ops*2^-53 << 1. The bound is a
+// worst case, typical errors are much smaller, growing as sqrt(ops).
+// A result above maxUint64, including gamma_ops = +Inf for ops >= 2^53,
+// is clamped to maxUint64.
+// Special cases:
+// ExpectedUlpError(c, ops <= 0)   = 0
+// ExpectedUlpError(NaN, ops)      = maxUint64
+// ExpectedUlpError(c <= 0, ops)   = 0
+//
+func ExpectedUlpError(condNumber float64, ops int) uint64 {
+	if ops <= 0 || condNumber <= 0 {
+		return 0
+	}
+	e := math.Ceil(condNumber * GammaBound(ops, 0) / unitRoundoff)
+	if !(e < 0x1p64) {
+		return maxUint64
+	}
+	return uint64(e)
+}
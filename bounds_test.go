@@ -0,0 +1,56 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+// ------------------------------------------------------------- Tests
+func TestErrorBoundAfterOps(t *testing.T) {
+	u := 0x1p-53
+	for _, n := range []int{1, 2, 10, 1000, 1e6, 1e12} {
+		e := ErrorBoundAfterOps(n, 0)
+		g := GammaBound(n, 0)
+		nu := float64(n) * u
+		t.Logf("n %-14d n*u %-24v bound %-24v gamma %v", n, nu, e, g)
+		if !(nu <= e && e <= g && g <= 1.0102*nu) {
+			t.Fatalf("n %d: n*u %v, bound %v, gamma %v", n, nu, e, g)
+		}
+	}
+	if e := ErrorBoundAfterOps(1, u); e != u {
+		t.Fatalf("ErrorBoundAfterOps(1, u) = %v", e)
+	}
+	// (1 + 0.5)^2 - 1 = 1.25, but gamma_2 is not valid for n*u = 1.
+	if e, g := ErrorBoundAfterOps(2, 0.5), GammaBound(2, 0.5); e != 1.25 || !math.IsInf(g, 1) {
+		t.Fatalf("bound %v, gamma %v", e, g)
+	}
+	if ErrorBoundAfterOps(0, u) != 0 || GammaBound(-1, u) != 0 {
+		t.Fatalf("n <= 0")
+	}
+}
+
+func TestExpectedUlpError(t *testing.T) {
+	cases := []struct {
+		cond float64
+		ops  int
+		want uint64
+	}{
+		{1, 1, 2},                 // gamma_1 / 2^-53 = 1/(1-2^-53) > 1
+		{1, 10, 11},
+		{100, 10, 1001},
+		{1e3, 1000, 1000001},
+		{1e20, 1000, maxUint64},
+		{math.Inf(1), 1, maxUint64},
+		{math.NaN(), 1, maxUint64},
+		{1, 1 << 53, maxUint64},
+		{1, 0, 0},
+		{0, 10, 0},
+	}
+	for _, c := range cases {
+		u := ExpectedUlpError(c.cond, c.ops)
+		t.Logf("cond %-8g ops %-18d %d", c.cond, c.ops, u)
+		if u != c.want {
+			t.Fatalf("ExpectedUlpError(%v, %d) = %d, want %d", c.cond, c.ops, u, c.want)
+		}
+	}
+}
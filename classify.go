@@ -0,0 +1,130 @@
+package fbits
+
+import (
+	"fmt"
+	"math"
+)
+
+// FloatClass is the IEEE 754 class of a float64.
+type FloatClass int
+
+const (
+	Zero FloatClass = iota     // +/-0
+	Subnormal                  // 0 < abs(x) < 2^-1022
+	Normal                     // 2^-1022 <= abs(x) <= MaxFloat64
+	Infinity                   // +/-Inf
+	NaN                        // any NaN, quiet or signaling
+)
+
+var classNames = [...]string{"Zero", "Subnormal", "Normal", "Infinity", "NaN"}
+
+func (c FloatClass) String() string {
+	if c < Zero || c > NaN {
+		return fmt.Sprintf("FloatClass(%d)", int(c))
+	}
+	return classNames[c]
+}
+
+// Classify returns the class of x from the exponent and significand bits.
+// The sign bit is ignored.
+//
+func Classify(x float64) FloatClass {
+	u := math.Float64bits(x) &^ signbit
+	switch {
+	case u == 0:
+		return Zero
+	case u < 1<<52:                 // exponent bits are zero
+		return Subnormal
+	case u < posInf:
+		return Normal
+	case u == posInf:
+		return Infinity
+	}
+	return NaN
+}
+
+// ClassCounts holds element counts indexed by FloatClass.
+type ClassCounts [5]int
+
+// String returns the counts as "Zero n, Subnormal n, Normal n, Infinity n, NaN n".
+func (c ClassCounts) String() string {
+	return fmt.Sprintf("Zero %d, Subnormal %d, Normal %d, Infinity %d, NaN %d",
+		c[Zero], c[Subnormal], c[Normal], c[Infinity], c[NaN])
+}
+
+// ClassifyCounts returns how many elements of s fall into each FloatClass.
+//
+// The slice is scanned once. The result is indexed by FloatClass,
+// e.g. ClassifyCounts(s)[NaN] is the number of NaNs in s.
+// ClassCounts converts to [5]int and prints as a one line summary.
+//
+func ClassifyCounts(s []float64) (c ClassCounts) {
+	for _, x := range s {
+		c[Classify(x)]++
+	}
+	return
+}
+
+// SeedCorpus returns a new slice of float64's which are interesting
+// inputs for numeric code, eg. for f.Add in fuzz tests.
+//
+// The corpus has, both signs for all but the NaNs:
+// 0, 2^-1074 (smallest subnormal), 2^-1022 - 2^-1074 (largest subnormal),
+// 2^-1022 (smallest normal), 1, MaxFloat64, Inf,
+// the floats just below and above 1, 2 and 2^53,
+// a quiet NaN (0x7ff8000000000001) and a signaling NaN (0x7ff0000000000001).
+// The signaling NaN may be quieted by the first arithmetic operation.
+//
+func SeedCorpus() []float64 {
+	s := []float64{
+		0, 0x1p-1074, 0x1p-1022 - 0x1p-1074, 0x1p-1022, 1, math.MaxFloat64, math.Inf(1),
+		NextToZero(1), NextFromZero(1), NextToZero(2), NextFromZero(2),
+		NextToZero(0x1p53), NextFromZero(0x1p53),
+	}
+	for _, x := range s {
+		s = append(s, -x)
+	}
+	return append(s,
+		math.Float64frombits(0x7ff8000000000001),    // quiet NaN, as math.NaN()
+		math.Float64frombits(0x7ff0000000000001))    // signaling NaN
+}
+
+// SubnormalDisagreement returns true if one of x and y is subnormal and
+// the other is zero, ie. they would be equal if subnormals were flushed
+// to zero.
+//
+// This is the difference between a platform with gradual underflow and one
+// which flushes subnormal results (FTZ) or operands (DAZ) to zero.
+// The sign of the zero is ignored, since flushing an operand can change
+// the sign of a zero result. Two distinct subnormals both flush to zero,
+// but they don't disagree in this sense and the result is false.
+//
+func SubnormalDisagreement(x, y float64) bool {
+	return x != y && flushSubnormal(x) == flushSubnormal(y) && (x == 0 || y == 0)
+}
+
+// flushSubnormal returns x, or a zero of the sign of x if x is subnormal.
+func flushSubnormal(x float64) float64 {
+	if Classify(x) == Subnormal {
+		return math.Copysign(0, x)
+	}
+	return x
+}
+
+// IsNegativeZero returns true if x is -0, Float64bits(x) == signbit.
+// -0 == 0 is true, so a comparison doesn't tell -0 from +0.
+func IsNegativeZero(x float64) bool {
+	return math.Float64bits(x) == signbit
+}
+
+// ContainsNegativeZero returns true and the index of the first -0 in s,
+// or false, -1 if s has no -0. A test can assert with it that a computation
+// didn't produce -0 where +0 was expected.
+func ContainsNegativeZero(s []float64) (bool, int) {
+	for i, x := range s {
+		if math.Float64bits(x) == signbit {
+			return true, i
+		}
+	}
+	return false, -1
+}
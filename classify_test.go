@@ -0,0 +1,164 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+func BenchmarkClassifyCounts(b *testing.B) {
+	s := make([]float64, 1<<16)
+	state := uint64(1)
+	for i := range s {
+		s[i] = math.Float64frombits(Splitmix(&state))
+	}
+	var c ClassCounts
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c = ClassifyCounts(s)
+	}
+	isink = c[Normal]
+}
+
+func BenchmarkSubnormalDisagreement(b *testing.B) {
+	var d bool
+	for n := 0; n < b.N; n++ {
+		d = SubnormalDisagreement(float64(n)*0x1p-1060, 0)
+	}
+	bsink = d
+}
+
+func BenchmarkContainsNegativeZero(b *testing.B) {
+	s := randomSlice(1000)
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is, _ = ContainsNegativeZero(s)
+	}
+	bsink = is
+}
+
+// ------------------------------------------------------------- Tests
+func TestClassifyCounts(t *testing.T) {
+	zero, inf, nan := 0.0, math.Inf(1), math.NaN()
+	s := []float64{
+		zero, -zero,
+		0x1p-1074, -0x1p-1074, 0x1p-1022 - 0x1p-1074,
+		1, -1, 0x1p-1022, math.MaxFloat64,
+		inf, -inf,
+		nan, math.Float64frombits(0x7ff0000000000001),
+	}
+	want := [5]int{2, 3, 4, 2, 2}
+	c := ClassifyCounts(s)
+	t.Logf("%v", c)
+	if c != want {
+		t.Fatalf("ClassifyCounts %v, want %v", c, ClassCounts(want))
+	}
+	if ClassifyCounts(nil) != (ClassCounts{}) {
+		t.Fatalf("ClassifyCounts(nil) %v", ClassifyCounts(nil))
+	}
+}
+
+func TestSeedCorpus(t *testing.T) {
+	c := SeedCorpus()
+	want := []struct {
+		x     float64
+		class FloatClass
+	}{
+		{0, Zero},
+		{math.Copysign(0, -1), Zero},
+		{0x1p-1074, Subnormal},
+		{-0x1p-1074, Subnormal},
+		{0x1p-1022 - 0x1p-1074, Subnormal},
+		{-0x1p-1022 + 0x1p-1074, Subnormal},
+		{0x1p-1022, Normal},
+		{-0x1p-1022, Normal},
+		{1, Normal},
+		{-1, Normal},
+		{math.MaxFloat64, Normal},
+		{-math.MaxFloat64, Normal},
+		{math.Inf(1), Infinity},
+		{math.Inf(-1), Infinity},
+		{NextFromZero(1), Normal},
+		{-NextToZero(2), Normal},
+		{math.Float64frombits(0x7ff8000000000001), NaN},
+		{math.Float64frombits(0x7ff0000000000001), NaN},
+	}
+	for _, w := range want {
+		found := false
+		for _, x := range c {
+			if math.Float64bits(x) == math.Float64bits(w.x) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("%v (%X) not in corpus", w.x, math.Float64bits(w.x))
+		}
+		if Classify(w.x) != w.class {
+			t.Fatalf("Classify(%v) = %v, want %v", w.x, Classify(w.x), w.class)
+		}
+	}
+	t.Logf("%v", ClassifyCounts(c))
+	c[0] = 5
+	if SeedCorpus()[0] != 0 {
+		t.Fatalf("SeedCorpus returned a shared slice")
+	}
+}
+
+func TestSubnormalDisagreement(t *testing.T) {
+	zero := 0.0
+	cases := []struct {
+		x, y float64
+		want bool
+	}{
+		{0x1p-1074, 0, true},
+		{-0x1p-1030, 0, true},
+		{0x1p-1022 - 0x1p-1074, -zero, true},
+		{0x1p-1074, 2 * 0x1p-1074, false},
+		{0x1p-1022, 0, false},                 // smallest normal
+		{1, 0, false},
+		{1, 2, false},
+		{0, -zero, false},
+		{0x1p-1074, 0x1p-1074, false},
+		{math.NaN(), 0, false},
+		{math.NaN(), 0x1p-1074, false},
+	}
+	for _, c := range cases {
+		d := SubnormalDisagreement(c.x, c.y)
+		t.Logf("%-24v %-24v %v", c.x, c.y, d)
+		if d != c.want || SubnormalDisagreement(c.y, c.x) != c.want {
+			t.Fatalf("SubnormalDisagreement(%v, %v) = %v", c.x, c.y, d)
+		}
+	}
+}
+
+func TestIsNegativeZero(t *testing.T) {
+	nz := math.Copysign(0, -1)
+	for _, x := range SeedCorpus() {
+		if IsNegativeZero(x) != (x == 0 && math.Signbit(x)) {
+			t.Fatalf("IsNegativeZero(%v) = %v", x, IsNegativeZero(x))
+		}
+	}
+	if !IsNegativeZero(nz) || IsNegativeZero(0) || IsNegativeZero(-0x1p-1074) || IsNegativeZero(-1) {
+		t.Fatalf("IsNegativeZero")
+	}
+	zero, tiny := 0.0, -0x1p-1074
+	if !IsNegativeZero(-1*zero) || !IsNegativeZero(tiny/4) || IsNegativeZero(nz+zero) {
+		t.Fatalf("IsNegativeZero of results")
+	}
+	cases := []struct {
+		s     []float64
+		found bool
+		i     int
+	}{
+		{nil, false, -1},
+		{[]float64{0, -1, -0x1p-1074, math.Inf(-1), math.NaN()}, false, -1},
+		{[]float64{0, -1, nz, nz}, true, 2},
+		{[]float64{nz}, true, 0},
+	}
+	for _, c := range cases {
+		found, i := ContainsNegativeZero(c.s)
+		t.Logf("%v  %v %d", c.s, found, i)
+		if found != c.found || i != c.i {
+			t.Fatalf("ContainsNegativeZero(%v) = %v, %d", c.s, found, i)
+		}
+	}
+}
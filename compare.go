@@ -0,0 +1,213 @@
+package fbits
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// CompareFuncs evaluates f and g at the inputs and returns the largest
+// UlpsBetween(f(x), g(x)), the first input x giving it, and a histogram
+// of the ulp differences.
+//
+// The histogram key for a difference of d ulps is bits.Len64(d):
+// 0 for equal results, 1 for 1 ulp, 2 for 2-3 ulps, k for [2^(k-1), 2^k) ulps.
+// If both f(x) and g(x) are NaN the results agree, the difference is 0.
+// If only one is NaN the difference is maxUint64, key 64.
+// For empty inputs maxUlps is 0 and worstInput is NaN.
+//
+func CompareFuncs(f, g func(float64) float64, inputs []float64) (maxUlps uint64, worstInput float64, histogram map[int]int) {
+	histogram = make(map[int]int)
+	worstInput = math.NaN()
+	for i, x := range inputs {
+		y, z := f(x), g(x)
+		var d uint64
+		if y == y || z == z {
+			d = UlpsBetween(y, z)
+		}
+		histogram[bits.Len64(d)]++
+		if d > maxUlps || i == 0 {
+			maxUlps, worstInput = d, x
+		}
+	}
+	return
+}
+
+// IsMonotoneOnGrid returns true, -1 if f is non-decreasing on inputs,
+// which should be sorted in increasing order.
+//
+// The outputs are compared in the IEEE 754 total order, so f(inputs[i])
+// must not be before f(inputs[i-1]). A -0 after +0 is a decrease.
+// Otherwise IsMonotoneOnGrid returns false and the first index i where the
+// output decreases. A NaN output is a violation at its own index.
+// For a non-increasing f, check the negated function.
+//
+func IsMonotoneOnGrid(f func(float64) float64, inputs []float64) (bool, int) {
+	var prev float64
+	for i, x := range inputs {
+		y := f(x)
+		if y != y || i > 0 && totalLess(y, prev) {
+			return false, i
+		}
+		prev = y
+	}
+	return true, -1
+}
+
+// BitsEqualCanonical returns true if x and y have the same bits,
+// or both are NaNs.
+//
+// NaN payloads and signs are ignored, but -0 and +0 differ. This is the
+// equality for comparing stored or serialized floats:
+// x == y is false for NaNs and true for -0 and +0, and
+// reflect.DeepEqual is like ==, except for NaNs at the same address.
+// Special cases:
+// BitsEqualCanonical(NaN, -NaN)  = true
+// BitsEqualCanonical(-0, 0)      = false
+//
+func BitsEqualCanonical(x, y float64) bool {
+	return math.Float64bits(x) == math.Float64bits(y) || x != x && y != y
+}
+
+// Tolerance is the ulp tolerance for results with
+// MinLog2 <= Log2(want) <= MaxLog2.
+type Tolerance struct {
+	MinLog2, MaxLog2 int
+	MaxUlps          uint64
+}
+
+// ToleranceTable is a list of ulp tolerances by the magnitude of the
+// expected result, for functions whose accuracy varies by input range.
+//
+//	tt := ToleranceTable{
+//		{-1, 0, 1},         // 0.5 <= abs(want) < 2
+//		{-1074, 1024, 4},   // everything else
+//	}
+//
+type ToleranceTable []Tolerance
+
+// Check returns true if UlpsBetween(got, want) is at most the tolerance
+// of want.
+//
+// The tolerance is MaxUlps of the first entry whose range contains
+// Log2(want). Zero has Log2 -1075 and Inf and NaN 1024. If no entry
+// matches, got must be equal to want. Two NaNs are always equal.
+//
+func (tt ToleranceTable) Check(got, want float64) bool {
+	if got != got && want != want {
+		return true
+	}
+	d := UlpsBetween(got, want)
+	e := Log2(want)
+	for _, tol := range tt {
+		if tol.MinLog2 <= e && e <= tol.MaxLog2 {
+			return d <= tol.MaxUlps
+		}
+	}
+	return d == 0
+}
+
+// FindWorstInput evaluates f and ref at samples inputs from [lo, hi] and
+// returns the input with the largest UlpsBetween(f(x), ref(x)), the first
+// one at ties, and the distance.
+//
+// The inputs are equally spaced in the order of the float64 values, in
+// ulps. This is close to logarithmic spacing, each binade of the interval
+// gets about the same number of samples, and the interval can contain zero.
+// Both lo and hi are sampled. The comparison is CompareFuncs, so results
+// which are both NaN agree. If lo > hi they are swapped.
+// Special cases:
+// FindWorstInput(f, ref, lo, hi, samples <= 0)  = NaN, 0
+// FindWorstInput(f, ref, lo, hi, 1)             = lo, UlpsBetween(f(lo), ref(lo))
+// FindWorstInput(f, ref, NaN, hi, samples)      = NaN, 0
+//
+func FindWorstInput(f, ref func(float64) float64, lo, hi float64, samples int) (worst float64, ulps uint64) {
+	if samples <= 0 || lo != lo || hi != hi {
+		return math.NaN(), 0
+	}
+	if totalLess(hi, lo) {
+		lo, hi = hi, lo
+	}
+	ulps, worst, _ = CompareFuncs(f, ref, ordinalGrid(lo, hi, samples))
+	return
+}
+
+// LessAbs returns true if abs(x) < abs(y).
+//
+// The bits of x and y without the sign bit are compared as integers,
+// which orders the magnitudes of non-NaN floats. A NaN is above +Inf in
+// this order, so LessAbs checks that y is not NaN and is false for NaNs
+// as abs(x) < abs(y) is.
+// Special cases:
+// LessAbs(-0, 0)      = false
+// LessAbs(x, NaN)     = false
+// LessAbs(NaN, y)     = false
+//
+func LessAbs(x, y float64) bool {
+	a := math.Float64bits(x) &^ signbit
+	b := math.Float64bits(y) &^ signbit
+	return a < b && b <= posInf
+}
+
+// CompareResult is the comparison of two floats from CompareFloats.
+type CompareResult struct {
+	Equal      bool       // x == y
+	Ulps       uint64     // UlpsBetween(x, y)
+	Relative   float64    // abs(x - y) / abs(y)
+	Hex1, Hex2 string     // x and y as hex floats, eg. 0x1.999999999999ap-04
+}
+
+// CompareFloats returns the diagnostics of x against the reference value y
+// for a test failure message.
+//
+// Relative is the relative error of x, 0 if x == y, +Inf if y is zero and
+// x is not, and NaN if x or y is NaN or both are the same Inf.
+// Hex1 and Hex2 are strconv.FormatFloat(x, 'x', -1, 64), the exact values.
+//
+func CompareFloats(x, y float64) CompareResult {
+	r := CompareResult{
+		Equal: x == y,
+		Ulps:  UlpsBetween(x, y),
+		Hex1:  strconv.FormatFloat(x, 'x', -1, 64),
+		Hex2:  strconv.FormatFloat(y, 'x', -1, 64),
+	}
+	if x != y || IsInf(x) {
+		r.Relative = math.Abs(x-y) / math.Abs(y)
+	}
+	return r
+}
+
+// SlicesEqual returns true if a and b have the same length and bit-equal
+// elements. With canonicalizeNaN any two NaNs are equal, as in
+// BitsEqualCanonical, otherwise NaNs must have the same bits.
+//
+// -0 and +0 are always different. This is the equality for stored or
+// serialized float arrays, where == would collapse the zeros and
+// reflect.DeepEqual makes NaNs unequal.
+//
+func SlicesEqual(a, b []float64, canonicalizeNaN bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Float64bits(a[i]) != math.Float64bits(b[i]) &&
+			!(canonicalizeNaN && a[i] != a[i] && b[i] != b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// BitsOfAgreement returns the number of leading bits, sign, exponent and
+// significand, which x and y have in common, 64 for bit-equal x and y.
+//
+// The count is bits.LeadingZeros64 of the xor of the bit patterns. It is
+// finer than the ulp distance inside a binade, but not monotone in it:
+// adjacent floats across a carry share few bits, 1 and NextToZero(1)
+// (0x3ff0000000000000 and 0x3fefffffffffffff) only 11.
+// Floats of different signs have 0 bits in common, also -0 and +0.
+// NaNs are compared as bit patterns.
+//
+func BitsOfAgreement(x, y float64) int {
+	return bits.LeadingZeros64(math.Float64bits(x) ^ math.Float64bits(y))
+}
@@ -0,0 +1,333 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+func BenchmarkBitsEqualCanonical(b *testing.B) {
+	var eq bool
+	for n := 0; n < b.N; n++ {
+		eq = BitsEqualCanonical(float64(n), 1)
+	}
+	bsink = eq
+}
+
+func BenchmarkLessAbs(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is = LessAbs(float64(n), -1000)
+	}
+	bsink = is
+}
+
+func BenchmarkLessAbsFloat(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is = math.Abs(float64(n)) < math.Abs(-1000)
+	}
+	bsink = is
+}
+
+func BenchmarkSlicesEqual(b *testing.B) {
+	x := randomSlice(1000)
+	y := append([]float64(nil), x...)
+	var eq bool
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		eq = SlicesEqual(x, y, true)
+	}
+	bsink = eq
+}
+
+func BenchmarkBitsOfAgreement(b *testing.B) {
+	var k int
+	for n := 0; n < b.N; n++ {
+		k = BitsOfAgreement(float64(n), 1000)
+	}
+	isink = k
+}
+
+// ------------------------------------------------------------- Tests
+func TestCompareFuncs(t *testing.T) {
+	inputs := randomSlice(100000)
+	for i := 0; i < 1000; i++ {
+		inputs = append(inputs, float64(i)*0x1p-1030)      // subnormals and small normals
+	}
+	inputs = append(inputs, 0, math.Inf(1), math.NaN())
+	ref := func(x float64) float64 { return math.Nextafter(x, 0) }
+
+	maxUlps, worst, h := CompareFuncs(NextToZero, ref, inputs)
+	t.Logf("NextToZero    max %d  worst %v  %v", maxUlps, worst, h)
+	if maxUlps != 0 || h[0] != len(inputs) {
+		t.Fatalf("NextToZero: max %d, worst %v", maxUlps, worst)
+	}
+	// NextToZeroFP fails and returns x in (0, 2^-1022], 1 ulp off.
+	maxUlps, worst, h = CompareFuncs(NextToZeroFP, ref, inputs)
+	t.Logf("NextToZeroFP  max %d  worst %v  %v", maxUlps, worst, h)
+	if maxUlps != 1 || abs(worst) > 0x1p-1022 || h[1] == 0 {
+		t.Fatalf("NextToZeroFP: max %d, worst %v", maxUlps, worst)
+	}
+	maxUlps, worst, _ = CompareFuncs(NextToZero, ref, nil)
+	if maxUlps != 0 || worst == worst {
+		t.Fatalf("empty inputs: max %d, worst %v", maxUlps, worst)
+	}
+}
+
+func TestIsMonotoneOnGrid(t *testing.T) {
+	grid := AdjacentSequence(-0x1p-1072, 10)
+	grid = append(grid, 0.5, 1, NextFromZero(1), 2, 1e300, math.Inf(1))
+	grid = append([]float64{math.Inf(-1), -2}, grid...)
+	identity := func(x float64) float64 { return x }
+	ok, i := IsMonotoneOnGrid(identity, grid)
+	t.Logf("identity   %v %d", ok, i)
+	if !ok || i != -1 {
+		t.Fatalf("identity: %v %d", ok, i)
+	}
+	for _, f := range []func(float64) float64{math.Exp, math.Cbrt, math.Atan, math.Floor} {
+		if ok, i := IsMonotoneOnGrid(f, grid); !ok {
+			t.Fatalf("monotone function decreases at %d, %v", i, grid[i])
+		}
+	}
+	ok, i = IsMonotoneOnGrid(math.Abs, grid)
+	t.Logf("abs        %v %d", ok, i)
+	if ok || i != 1 {
+		t.Fatalf("abs: %v %d", ok, i)
+	}
+	ok, i = IsMonotoneOnGrid(math.Sqrt, grid)
+	t.Logf("sqrt       %v %d", ok, i)
+	if ok || i != 0 {
+		t.Fatalf("sqrt: %v %d", ok, i)
+	}
+	ok, i = IsMonotoneOnGrid(func(x float64) float64 { return -x * 0 }, []float64{-1, 1})
+	if ok || i != 1 {
+		t.Fatalf("+0, -0: %v %d", ok, i)
+	}
+	if ok, i := IsMonotoneOnGrid(identity, nil); !ok || i != -1 {
+		t.Fatalf("empty inputs")
+	}
+}
+
+func TestBitsEqualCanonical(t *testing.T) {
+	zero, inf := 0.0, math.Inf(1)
+	nan1 := math.NaN()
+	nan2 := math.Float64frombits(0xfff0000000000123)
+	cases := []struct {
+		x, y float64
+		want bool
+	}{
+		{zero, zero, true},
+		{zero, -zero, false},
+		{-zero, -zero, true},
+		{nan1, nan1, true},
+		{nan1, nan2, true},
+		{nan1, inf, false},
+		{inf, inf, true},
+		{1, 1, true},
+		{1, NextFromZero(1), false},
+		{0x1p-1074, 0x1p-1074, true},
+		{-1, 1, false},
+	}
+	for _, c := range cases {
+		eq := BitsEqualCanonical(c.x, c.y)
+		t.Logf("%-6v %-6v %-6v ==  %v", c.x, c.y, eq, c.x == c.y)
+		if eq != c.want || BitsEqualCanonical(c.y, c.x) != c.want {
+			t.Fatalf("BitsEqualCanonical(%v, %v) = %v", c.x, c.y, eq)
+		}
+	}
+}
+
+func TestToleranceTable(t *testing.T) {
+	tt := ToleranceTable{
+		{-1, 0, 1},
+		{1, 10, 4},
+		{-1, 1000, 100},      // overlaps, the first match wins
+		{-1075, -1075, 2},    // zero
+	}
+	ulps := func(x float64, n int) float64 {
+		for ; n > 0; n-- {
+			x = NextFromZero(x)
+		}
+		return x
+	}
+	cases := []struct {
+		want float64
+		n    int
+		ok   bool
+	}{
+		{1, 1, true},
+		{1, 2, false},
+		{-0.5, 1, true},
+		{NextToZero(0.5), 1, false},     // Log2 -2, no entry
+		{NextToZero(0.5), 0, true},
+		{2, 4, true},
+		{1024, 5, false},
+		{2048, 5, true},
+		{2048, 100, true},
+		{2048, 101, false},
+		{0, 2, true},
+		{0, 3, false},
+		{math.Inf(1), 1, true},         // NextFromZero(Inf) is Inf
+		{math.MaxFloat64, 1, false},    // Inf is 1 ulp from MaxFloat64, Log2 1023
+	}
+	for _, c := range cases {
+		got := ulps(c.want, c.n)
+		ok := tt.Check(got, c.want)
+		t.Logf("%-22v %3d ulps  Log2 %5d  %v", c.want, c.n, Log2(c.want), ok)
+		if ok != c.ok {
+			t.Fatalf("Check(%v, %v) = %v", got, c.want, ok)
+		}
+	}
+	if !tt.Check(math.NaN(), math.NaN()) || tt.Check(1, math.NaN()) || tt.Check(math.NaN(), 1) {
+		t.Fatalf("NaN")
+	}
+	if !(ToleranceTable{}).Check(3, 3) || (ToleranceTable{}).Check(3, NextFromZero(3)) {
+		t.Fatalf("empty table")
+	}
+}
+
+func TestFindWorstInput(t *testing.T) {
+	inf := math.Inf(1)
+	next := func(x float64) float64 { return math.Nextafter(x, inf) }
+	worst, ulps := FindWorstInput(NextFromZeroFP, next, 0x1p-1074, 1, 10000)
+	t.Logf("NextFromZeroFP  %v (%x)  %d ulps", worst, worst, ulps)
+	if ulps != 1 || worst >= 0x1p-1019 {
+		t.Fatalf("NextFromZeroFP: %v %d", worst, ulps)
+	}
+	worst, ulps = FindWorstInput(NextFromZeroFP, next, 0x1p-1019, 1e300, 10000)
+	if ulps != 0 || worst != 0x1p-1019 {
+		t.Fatalf("NextFromZeroFP above 2^-1019: %v %d", worst, ulps)
+	}
+	// lo and hi are sampled, also across zero and in the reversed order.
+	for _, c := range [][2]float64{{-inf, inf}, {inf, -inf}, {-1, 2}, {3, 3}} {
+		var seen []float64
+		record := func(x float64) float64 { seen = append(seen, x); return x }
+		FindWorstInput(record, record, c[0], c[1], 7)
+		lo, hi := math.Min(c[0], c[1]), math.Max(c[0], c[1])
+		if len(seen) != 14 || seen[0] != lo || seen[12] != hi {
+			t.Fatalf("%v: samples %v", c, seen)
+		}
+		for i := 2; i < 14; i += 2 {
+			if totalLess(seen[i], seen[i-2]) {
+				t.Fatalf("%v: samples %v", c, seen)
+			}
+		}
+	}
+	if w, u := FindWorstInput(next, next, 1, math.NaN(), 10); w == w || u != 0 {
+		t.Fatalf("NaN")
+	}
+	if w, u := FindWorstInput(NextFromZeroFP, next, 0x1p-1030, 1, 1); w != 0x1p-1030 || u != 1 {
+		t.Fatalf("samples 1: %v %d", w, u)
+	}
+}
+
+func TestLessAbs(t *testing.T) {
+	const rounds int = 1e7
+	zero, inf, nan := 0.0, math.Inf(1), math.NaN()
+	special := []float64{zero, -zero, 0x1p-1074, -1, 1, math.MaxFloat64, inf, -inf, nan, -nan}
+	for _, x := range special {
+		for _, y := range special {
+			if LessAbs(x, y) != (math.Abs(x) < math.Abs(y)) {
+				t.Fatalf("LessAbs(%v, %v) = %v", x, y, LessAbs(x, y))
+			}
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		y := RandomFloat64(&state)
+		switch i & 3 {
+		case 0:
+			y = -x
+		case 1:
+			y = NextToward(x, 0)
+		case 2:
+			y = math.Float64frombits(Splitmix(&state))    // NaNs and Infs
+		}
+		if LessAbs(x, y) != (math.Abs(x) < math.Abs(y)) || LessAbs(y, x) != (math.Abs(y) < math.Abs(x)) {
+			t.Logf("x    %v", x)
+			t.Fatalf("y    %v", y)
+		}
+	}
+}
+
+func TestCompareFloats(t *testing.T) {
+	r := CompareFloats(NextFromZero(0.1), 0.1)
+	t.Logf("%+v", r)
+	if r.Equal || r.Ulps != 1 || r.Relative != Ulp(0.1)/0.1 ||
+		r.Hex1 != "0x1.999999999999bp-04" || r.Hex2 != "0x1.999999999999ap-04" {
+		t.Fatalf("adjacent: %+v", r)
+	}
+	r = CompareFloats(math.NaN(), 1)
+	t.Logf("%+v", r)
+	if r.Equal || r.Ulps != maxUint64 || r.Relative == r.Relative || r.Hex1 != "NaN" || r.Hex2 != "0x1p+00" {
+		t.Fatalf("NaN: %+v", r)
+	}
+	r = CompareFloats(math.NaN(), math.NaN())
+	if r.Equal || r.Ulps != maxUint64 {
+		t.Fatalf("NaN, NaN: %+v", r)
+	}
+	r = CompareFloats(math.Copysign(0, -1), 0)
+	if !r.Equal || r.Ulps != 0 || r.Relative != 0 {
+		t.Fatalf("zeros: %+v", r)
+	}
+	r = CompareFloats(1e-300, 0)
+	if r.Relative != math.Inf(1) {
+		t.Fatalf("y = 0: %+v", r)
+	}
+	r = CompareFloats(math.Inf(1), math.Inf(1))
+	if !r.Equal || r.Ulps != 0 || r.Relative == r.Relative {
+		t.Fatalf("Inf: %+v", r)
+	}
+}
+
+func TestSlicesEqual(t *testing.T) {
+	nz := math.Copysign(0, -1)
+	nan1, nan2 := math.NaN(), math.Float64frombits(0xfff8000000000007)
+	cases := []struct {
+		a, b        []float64
+		exact, canon bool
+	}{
+		{nil, nil, true, true},
+		{nil, []float64{}, true, true},
+		{[]float64{1, 2}, []float64{1, 2}, true, true},
+		{[]float64{1, 2}, []float64{1}, false, false},
+		{[]float64{1, nan1}, []float64{1, nan1}, true, true},
+		{[]float64{1, nan1}, []float64{1, nan2}, false, true},
+		{[]float64{0, 1}, []float64{nz, 1}, false, false},
+		{[]float64{nz}, []float64{nz}, true, true},
+		{[]float64{nan1}, []float64{1}, false, false},
+	}
+	for _, c := range cases {
+		e, n := SlicesEqual(c.a, c.b, false), SlicesEqual(c.a, c.b, true)
+		t.Logf("%v %v  %v %v", c.a, c.b, e, n)
+		if e != c.exact || n != c.canon || SlicesEqual(c.b, c.a, false) != e || SlicesEqual(c.b, c.a, true) != n {
+			t.Fatalf("SlicesEqual(%v, %v) = %v, %v", c.a, c.b, e, n)
+		}
+	}
+}
+
+func TestBitsOfAgreement(t *testing.T) {
+	cases := []struct {
+		x, y float64
+		want int
+	}{
+		{1, 1, 64},
+		{math.NaN(), math.NaN(), 64},
+		{1, NextFromZero(1), 63},
+		{2, NextFromZero(2), 63},
+		{1, NextToZero(1), 11},
+		{1.5, 1.75, 13},
+		{1, 2, 1},
+		{1, -1, 0},
+		{0, math.Copysign(0, -1), 0},
+		{0, 0x1p-1074, 63},
+	}
+	for _, c := range cases {
+		k := BitsOfAgreement(c.x, c.y)
+		t.Logf("%016X %016X  %d", math.Float64bits(c.x), math.Float64bits(c.y), k)
+		if k != c.want || BitsOfAgreement(c.y, c.x) != k {
+			t.Fatalf("BitsOfAgreement(%v, %v) = %d, want %d", c.x, c.y, k, c.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package fbits
+
+// UlpsBetweenComplex returns the larger of the distances in ulps between
+// the real parts and between the imaginary parts of x and y.
+//
+// The parts are compared separately, so the result does not depend on the
+// magnitude of x: the distance of a tiny imaginary part is counted in its
+// own ulps, not in ulps of abs(x).
+// Special cases:
+// UlpsBetweenComplex(x, y), a NaN part  = maxUint64
+//
+func UlpsBetweenComplex(x, y complex128) uint64 {
+	d := UlpsBetween(real(x), real(y))
+	if e := UlpsBetween(imag(x), imag(y)); e > d {
+		d = e
+	}
+	return d
+}
+
+// AlmostEqualComplex returns true if both the real parts and the imaginary
+// parts of x and y are at most maxUlps apart, UlpsBetweenComplex(x, y) <=
+// maxUlps. If any part of x or y is NaN, it returns false, also when
+// maxUlps is maxUint64. +0 and -0 parts are equal.
+//
+func AlmostEqualComplex(x, y complex128, maxUlps uint64) bool {
+	if IsNaN(real(x)) || IsNaN(imag(x)) || IsNaN(real(y)) || IsNaN(imag(y)) {
+		return false
+	}
+	return UlpsBetweenComplex(x, y) <= maxUlps
+}
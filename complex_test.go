@@ -0,0 +1,54 @@
+package fbits
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func BenchmarkAlmostEqualComplex(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		x := complex(float64(n), 1)
+		is = AlmostEqualComplex(x, x+1e-12i, 4)
+	}
+	bsink = is
+}
+
+// ------------------------------------------------------------- Tests
+func TestAlmostEqualComplex(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	up := NextFromZero(1.0)
+	cases := []struct {
+		x, y    complex128
+		maxUlps uint64
+		ulps    uint64
+		equal   bool
+	}{
+		{1 + 1i, 1 + 1i, 0, 0, true},
+		{complex(1, 1e-300), complex(up, NextFromZero(1e-300)), 1, 1, true},
+		{complex(1, 1e-300), complex(NextFromZero(up), 1e-300), 1, 2, false},
+		{complex(1, 0), complex(1, math.Copysign(0, -1)), 0, 0, true},
+		{complex(1, 0x1p-1074), complex(1, -0x1p-1074), 1, 2, false},
+		{complex(inf, 1), complex(math.MaxFloat64, 1), 1, 1, true},
+		{complex(nan, 1), complex(nan, 1), maxUint64, maxUint64, false},
+		{complex(1, nan), 1 + 1i, maxUint64, maxUint64, false},
+		{cmplx.Exp(1i * math.Pi), -1, 1 << 60, UlpsBetween(0, math.Sin(math.Pi)), false},   // imag 1.2e-16 is far from 0 in ulps
+	}
+	for _, c := range cases {
+		d, eq := UlpsBetweenComplex(c.x, c.y), AlmostEqualComplex(c.x, c.y, c.maxUlps)
+		t.Logf("%v %v  %d %v", c.x, c.y, d, eq)
+		if d != c.ulps || eq != c.equal {
+			t.Fatalf("%v %v: UlpsBetweenComplex %d, want %d; AlmostEqualComplex %v, want %v",
+				c.x, c.y, d, c.ulps, eq, c.equal)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < 1e5; i++ {
+		x := complex(RandomFloat64(&state), RandomFloat64(&state))
+		y := complex(NextFromZero(real(x)), NextToZero(imag(x)))
+		if !AlmostEqualComplex(x, y, 1) || AlmostEqualComplex(x, y, 0) || UlpsBetweenComplex(y, x) != 1 {
+			t.Fatalf("%v %v", x, y)
+		}
+	}
+}
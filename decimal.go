@@ -0,0 +1,133 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"sync"
+)
+
+// pow10ceil[k+324] is the smallest float64 >= 10^k, -324 <= k <= 308.
+// abs(x) >= pow10ceil[k+324] if and only if abs(x) >= 10^k exactly.
+// The table takes milliseconds of big.Rat arithmetic, so it is built on
+// first use and not at package init.
+var (
+	pow10ceil     [633]float64
+	pow10ceilOnce sync.Once
+)
+
+// pow10Ceil returns pow10ceil[k+324], the smallest float64 >= 10^k.
+func pow10Ceil(k int) float64 {
+	pow10ceilOnce.Do(pow10CeilTable)
+	return pow10ceil[k+324]
+}
+
+func pow10CeilTable() {
+	for k := -324; k <= 308; k++ {
+		p := pow10Rat(k)
+		f, _ := p.Float64()                           // nearest
+		if new(big.Rat).SetFloat64(f).Cmp(p) < 0 {
+			f = math.Nextafter(f, math.Inf(1))
+		}
+		pow10ceil[k+324] = f
+	}
+}
+
+// pow10Rat returns 10^k exactly.
+func pow10Rat(k int) *big.Rat {
+	n := int64(k)
+	if k < 0 {
+		n = -n
+	}
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
+	if k < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+// DecimalExponent returns floor(log10(abs(x))), the decimal exponent of x
+// in scientific notation, 10^n <= abs(x) < 10^(n+1).
+//
+// n is first estimated from Log2(x) as floor(Log2(x) * log10(2)), with
+// log10(2) approximated by 78913 / 2^18, which is exact for all float64
+// exponents. The estimate is n or n-1 and it is corrected by one compare to
+// a table of powers of ten, rounded up. The result is exact also when x is
+// close to a power of ten, where math.Floor(math.Log10(x)) may be off by one.
+// Special cases:
+// DecimalExponent(+/-0)     = -325
+// DecimalExponent(+/-Inf)   = 309
+// DecimalExponent(NaN)      = 309
+// DecimalExponent(-x)       = DecimalExponent(x)
+//
+func DecimalExponent(x float64) int {
+	x = math.Abs(x)
+	switch {
+	case x == 0:
+		return -325
+	case !(x <= maxFloat64):                  // Inf or NaN
+		return 309
+	}
+	n := (Log2(x) * 78913) >> 18              // arithmetic shift, rounds towards -Inf
+	if x >= pow10Ceil(n+1) {
+		n++
+	}
+	return n
+}
+
+// MinUlpsToChangeDecimal returns the least number of ulps x must move up
+// or down before strconv.FormatFloat(x, 'g', prec, 64) is a different string.
+//
+// The floats which format to the same string are an interval of the
+// ordinals, so in both directions the distance is found by doubling the
+// step until the string changes and bisecting, about 2*64 formats at most.
+// For prec -1, the shortest representation which parses back to x, the
+// result is 1 as any other float has a different string. Stepping ends at
+// +/-Inf, +0 and -0 are one point and -0 formats as "-0".
+// Special cases:
+// MinUlpsToChangeDecimal(x, -1)      = 1
+// MinUlpsToChangeDecimal(NaN, prec)  = maxUint64
+//
+func MinUlpsToChangeDecimal(x float64, prec int) uint64 {
+	if x != x {
+		return maxUint64
+	}
+	s := strconv.FormatFloat(x, 'g', prec, 64)
+	k := ordinal(x)
+	min := uint64(maxUint64)
+	for _, dir := range []int64{1, -1} {
+		changed := func(d uint64) bool {      // k + dir*d wraps correctly for d >= 2^63
+			return strconv.FormatFloat(fromOrdinal(k+dir*int64(d)), 'g', prec, 64) != s
+		}
+		limit := uint64(0x7ff0000000000000 - dir*k)    // ulps to +/-Inf, < 2^64
+		if limit == 0 {
+			continue
+		}
+		lo, hi := uint64(0), uint64(1)            // unchanged at lo
+		for !changed(hi) {
+			if hi == limit {
+				hi = 0
+				break
+			}
+			lo, hi = hi, 2*hi
+			if hi > limit || hi == 0 {
+				hi = limit
+			}
+		}
+		if hi == 0 {
+			continue
+		}
+		for hi-lo > 1 {                           // changed at hi
+			m := lo + (hi-lo)/2
+			if changed(m) {
+				hi = m
+			} else {
+				lo = m
+			}
+		}
+		if hi < min {
+			min = hi
+		}
+	}
+	return min
+}
@@ -0,0 +1,142 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+func BenchmarkDecimalExponent(b *testing.B) {
+	var k int
+	for n := 0; n < b.N; n++ {
+		k = DecimalExponent(float64(n) * 1.5e-3)
+	}
+	isink = k
+}
+
+func BenchmarkMathLog10(b *testing.B) {
+	var k int
+	for n := 0; n < b.N; n++ {
+		k = int(math.Floor(math.Log10(float64(n) * 1.5e-3)))
+	}
+	isink = k
+}
+
+func BenchmarkMinUlpsToChangeDecimal(b *testing.B) {
+	var u uint64
+	for n := 0; n < b.N; n++ {
+		u = MinUlpsToChangeDecimal(float64(n)*1.1, 6)
+	}
+	usink = u
+}
+
+// ------------------------------------------------------------- Tests
+// isDecimalExponent reports whether 10^n <= abs(x) < 10^(n+1) exactly.
+func isDecimalExponent(x float64, n int) bool {
+	r := new(big.Rat).SetFloat64(math.Abs(x))
+	return pow10Rat(n).Cmp(r) <= 0 && r.Cmp(pow10Rat(n+1)) < 0
+}
+
+func TestDecimalExponent(t *testing.T) {
+	const rounds int = 1e6
+	cases := []struct {
+		x    float64
+		want int
+	}{
+		{1, 0},
+		{9.999999999999999, 0},
+		{10, 1},
+		{-10, 1},
+		{0.1, -1},                     // float64(0.1) > 1/10
+		{NextToZero(0.1), -2},
+		{1e22, 22},
+		{1e23, 22},                    // float64(1e23) < 10^23
+		{NextFromZero(1e23), 23},
+		{math.MaxFloat64, 308},
+		{0x1p-1074, -324},
+		{0x1p-1022, -308},
+		{0, -325},
+		{math.Inf(-1), 309},
+		{math.NaN(), 309},
+	}
+	for _, c := range cases {
+		n := DecimalExponent(c.x)
+		t.Logf("%-24v %4d  %v", c.x, n, math.Floor(math.Log10(math.Abs(c.x))))
+		if n != c.want {
+			t.Fatalf("DecimalExponent(%v) = %d, want %d", c.x, n, c.want)
+		}
+	}
+	for k := -323; k <= 308; k++ {
+		x := pow10Ceil(k)
+		if !isDecimalExponent(x, k) || !isDecimalExponent(NextToZero(x), k-1) {
+			t.Fatalf("pow10Ceil(%d) = %v", k, x)
+		}
+	}
+	state := uint64(1)
+	differ := 0
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		if !IsFinite(x) || x == 0 {
+			continue
+		}
+		n := DecimalExponent(x)
+		if n != int(math.Floor(math.Log10(math.Abs(x)))) {
+			differ++
+			if !isDecimalExponent(x, n) {
+				t.Fatalf("DecimalExponent(%v) = %d", x, n)
+			}
+		}
+	}
+	// On amd64 math.Log10 of a subnormal can be wrong by more than one.
+	t.Logf("differ from math.Log10 %d", differ)
+}
+
+func TestMinUlpsToChangeDecimal(t *testing.T) {
+	const rounds int = 1e4
+	format := func(x float64, prec int) string { return strconv.FormatFloat(x, 'g', prec, 64) }
+	// "1" is [0.95, 1.5) for prec 1, 1.5 rounds to "2" and 0.95 is a little below 0.95.
+	down := UlpsBetween(1, 0.95)
+	if u := MinUlpsToChangeDecimal(1, 1); u != down || down < 1<<48 {
+		t.Fatalf("MinUlpsToChangeDecimal(1, 1) = %d, want %d", u, down)
+	}
+	if u := MinUlpsToChangeDecimal(1.25, 1); u != 1<<50 {
+		t.Fatalf("MinUlpsToChangeDecimal(1.25, 1) = %d, want 2^50", u)
+	}
+	cases := []struct {
+		x    float64
+		prec int
+		want uint64
+	}{
+		{1, -1, 1},
+		{0.1, -1, 1},
+		{math.Copysign(0, -1), -1, 1},
+		{math.MaxFloat64, -1, 1},
+		{math.Inf(1), -1, 1},
+		{math.Inf(-1), 3, 1},
+		{math.NaN(), 3, maxUint64},
+	}
+	for _, c := range cases {
+		u := MinUlpsToChangeDecimal(c.x, c.prec)
+		t.Logf("%-24v %3d  %d", c.x, c.prec, u)
+		if u != c.want {
+			t.Fatalf("MinUlpsToChangeDecimal(%v, %d) = %d, want %d", c.x, c.prec, u, c.want)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		prec := int(Splitmix(&state)%18) - 1
+		u := MinUlpsToChangeDecimal(x, prec)
+		k, s := ordinal(x), format(x, prec)
+		d := int64(u)
+		up := k+d <= 0x7ff0000000000000 && format(fromOrdinal(k+d), prec) != s
+		dn := k-d >= -0x7ff0000000000000 && format(fromOrdinal(k-d), prec) != s
+		if !up && !dn || prec == -1 && u != 1 {
+			t.Fatalf("MinUlpsToChangeDecimal(%v, %d) = %d, no change", x, prec, u)
+		}
+		if d > 1 && (format(fromOrdinal(k+d-1), prec) != s || format(fromOrdinal(k-d+1), prec) != s) {
+			t.Fatalf("MinUlpsToChangeDecimal(%v, %d) = %d, changes earlier", x, prec, u)
+		}
+	}
+}
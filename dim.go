@@ -38,6 +38,51 @@ func minFbits(x, y float64) float64 {
 // https://github.com/JuliaLang/julia/issues/7866
 // https://github.com/JuliaLang/julia/issues/10729
 
+// ComparePayload compares the payloads of NaNs x and y. The payload is the
+// low 51 significand bits, below the quiet bit. It returns
+//	-1 if payload(x) < payload(y)
+//	 0 if payload(x) == payload(y)
+//	+1 if payload(x) > payload(y)
+//	 2 if x or y is not a NaN
+// The sign bit and the quiet bit are not part of the payload.
+func ComparePayload(x, y float64) int {
+	if x == x || y == y {
+		return 2
+	}
+	const payload = 1<<51 - 1
+	p := math.Float64bits(x) & payload
+	q := math.Float64bits(y) & payload
+	switch {
+	case p < q:
+		return -1
+	case p > q:
+		return 1
+	}
+	return 0
+}
+
+// ResolveZeroSign returns the zero min or max chooses for zeros x and y.
+// With preferNegative it is the min policy of minFbits, minGo and
+// minGoProposal, -0 if x or y is -0. Otherwise it is the max policy,
+// +0 if x or y is +0. The sign is resolved by the bitwise or (and)
+// of x and y, as in minGoProposal.
+//
+// Special cases are:
+//	ResolveZeroSign(-0, ±0, true)   = ResolveZeroSign(±0, -0, true) = -0
+//	ResolveZeroSign(+0, +0, true)   = +0
+//	ResolveZeroSign(+0, ±0, false)  = ResolveZeroSign(±0, +0, false) = +0
+//	ResolveZeroSign(-0, -0, false)  = -0
+//	ResolveZeroSign(x, y, p)        = NaN, if x or y is not zero
+func ResolveZeroSign(x, y float64, preferNegative bool) float64 {
+	if x != 0 || y != 0 {
+		return math.NaN()
+	}
+	if preferNegative {
+		return math.Float64frombits(math.Float64bits(x) | math.Float64bits(y))
+	}
+	return math.Float64frombits(math.Float64bits(x) & math.Float64bits(y))
+}
+
 // Go standard library minGo returns the smaller of x or y.
 // https://golang.org/src/math/dim.go
 // Compiler: cannot inline minGo: function too complex: cost 138 exceeds budget 80
@@ -0,0 +1,63 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+// ------------------------------------------------------------- Tests
+func TestComparePayload(t *testing.T) {
+	nan1 := math.Float64frombits(0x7ff8000000000001)
+	nan2 := math.Float64frombits(0x7ff8000000000002)
+	snan2 := math.Float64frombits(0xfff0000000000002)       // negative signaling NaN
+	cases := []struct {
+		x, y float64
+		want int
+	}{
+		{nan1, nan2, -1},
+		{nan2, nan1, 1},
+		{nan2, snan2, 0},
+		{nan1, nan1, 0},
+		{nan1, 1, 2},
+		{math.Inf(1), nan1, 2},
+		{0, 0, 2},
+	}
+	for _, c := range cases {
+		r := ComparePayload(c.x, c.y)
+		t.Logf("%X %X  %d", math.Float64bits(c.x), math.Float64bits(c.y), r)
+		if r != c.want {
+			t.Fatalf("ComparePayload(%X, %X) = %d, want %d", math.Float64bits(c.x), math.Float64bits(c.y), r, c.want)
+		}
+	}
+	// Which payload survives x + y is up to the hardware.
+	t.Logf("nan1 + nan2    %X", math.Float64bits(nan1 + nan2))
+}
+
+func TestResolveZeroSign(t *testing.T) {
+	pz, nz := 0.0, math.Copysign(0, -1)
+	cases := []struct {
+		x, y           float64
+		min, max       float64
+	}{
+		{pz, pz, pz, pz},
+		{pz, nz, nz, pz},
+		{nz, pz, nz, pz},
+		{nz, nz, nz, nz},
+	}
+	for _, c := range cases {
+		mn, mx := ResolveZeroSign(c.x, c.y, true), ResolveZeroSign(c.x, c.y, false)
+		t.Logf("%v %v  min %v  max %v", math.Signbit(c.x), math.Signbit(c.y), math.Signbit(mn), math.Signbit(mx))
+		if mn != 0 || mx != 0 || math.Signbit(mn) != math.Signbit(c.min) || math.Signbit(mx) != math.Signbit(c.max) {
+			t.Fatalf("ResolveZeroSign(%v, %v): min %v, max %v", c.x, c.y, mn, mx)
+		}
+		if m := minFbits(c.x, c.y); math.Signbit(m) != math.Signbit(mn) {
+			t.Fatalf("minFbits(%v, %v) = %v", c.x, c.y, m)
+		}
+		if m := math.Max(c.x, c.y); math.Signbit(m) != math.Signbit(mx) {
+			t.Fatalf("math.Max(%v, %v) = %v", c.x, c.y, m)
+		}
+	}
+	if ResolveZeroSign(1, 0, true) == ResolveZeroSign(1, 0, true) || ResolveZeroSign(0, math.NaN(), false) == 0 {
+		t.Fatalf("nonzero arguments")
+	}
+}
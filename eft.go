@@ -0,0 +1,25 @@
+package fbits
+
+import (
+	"math"
+)
+
+// TwoDiv returns the rounded quotient quot = a/b and the error term err,
+// so that quot + err is a/b to about twice the float64 precision.
+//
+// The residual r = a - quot*b is exact as math.FMA(-quot, b, a), so
+// a == quot*b + r in exact arithmetic, and err = r/b is the remaining part
+// of the quotient, rounded. This holds if quot*b doesn't underflow,
+// roughly abs(a) > 2^-969, and a/b doesn't overflow.
+// If a, b or the quotient is not finite, err is NaN.
+// Special cases:
+// TwoDiv(a, +/-0)      = +/-Inf or NaN, NaN
+// TwoDiv(a, +/-Inf)    = +/-0, NaN
+// TwoDiv(+/-Inf, b)    = +/-Inf or NaN, NaN
+// TwoDiv(a, NaN)       = NaN, NaN
+//
+func TwoDiv(a, b float64) (quot, err float64) {
+	quot = a / b
+	err = math.FMA(-quot, b, a) / b
+	return
+}
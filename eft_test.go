@@ -0,0 +1,60 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func BenchmarkTwoDiv(b *testing.B) {
+	var q, e float64
+	for n := 0; n < b.N; n++ {
+		q, e = TwoDiv(float64(n), 3)
+	}
+	fsink = q + e
+}
+
+// ------------------------------------------------------------- Tests
+func TestTwoDiv(t *testing.T) {
+	const rounds int = 1e5
+	q, e := TwoDiv(1, 3)
+	t.Logf("1/3     %v  %v", q, e)
+	q, e = TwoDiv(1, 0)
+	t.Logf("1/0     %v  %v", q, e)
+	q, e = TwoDiv(1, math.Inf(1))
+	t.Logf("1/Inf   %v  %v", q, e)
+	if q != 0 || e == e {
+		t.Fatalf("1/Inf")
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		a := RandomFloat64(&state)
+		b := math.Ldexp(RandomFloat64(&state), -int(Splitmix(&state)%200))
+		b = math.Ldexp(b, Log2(a)-Log2(b)+int(Splitmix(&state)%200)-100)
+		if math.Abs(a) < 0x1p-900 || b == 0 || IsInf(b) {
+			continue
+		}
+		q, e := TwoDiv(a, b)
+		if !IsFinite(q) || math.Abs(q) < 0x1p-900 {
+			continue
+		}
+		// a == q*b + r exactly, with r = FMA(-q, b, a).
+		r := math.FMA(-q, b, a)
+		x := new(big.Float).SetPrec(2200).SetFloat64(q)
+		x.Mul(x, big.NewFloat(b))
+		x.Add(x, big.NewFloat(r))
+		if x.Cmp(big.NewFloat(a)) != 0 {
+			t.Logf("a    %v", a)
+			t.Fatalf("b    %v  residual not exact", b)
+		}
+		// q + e is a/b to about 104 bits.
+		exact := new(big.Float).SetPrec(2200).Quo(big.NewFloat(a), big.NewFloat(b))
+		diff := new(big.Float).SetPrec(2200).SetFloat64(q)
+		diff.Add(diff, big.NewFloat(e)).Sub(diff, exact).Quo(diff, exact)
+		if rel, _ := diff.Float64(); math.Abs(rel) > 0x1p-103 {
+			t.Logf("a    %v", a)
+			t.Logf("b    %v", b)
+			t.Fatalf("relative error %v", rel)
+		}
+	}
+}
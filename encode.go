@@ -0,0 +1,81 @@
+package fbits
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrLength is returned when a byte slice is not a whole number of float64's.
+var ErrLength = errors.New("fbits: byte slice length is not a multiple of 8")
+
+// ErrShort is returned when a byte slice is shorter than 8 bytes.
+var ErrShort = errors.New("fbits: byte slice is shorter than 8 bytes")
+
+// AppendFloat64LE appends the 8 bytes of math.Float64bits(x) to dst in
+// little-endian order. All bits are kept, including NaN payloads and -0.
+func AppendFloat64LE(dst []byte, x float64) []byte {
+	return binary.LittleEndian.AppendUint64(dst, math.Float64bits(x))
+}
+
+// AppendFloat64BE appends the 8 bytes of math.Float64bits(x) to dst in
+// big-endian order.
+func AppendFloat64BE(dst []byte, x float64) []byte {
+	return binary.BigEndian.AppendUint64(dst, math.Float64bits(x))
+}
+
+// MarshalFloat64Slice returns the bytes of the elements of s, 8 bytes each,
+// in big-endian order if bigEndian is true and otherwise in little-endian order.
+func MarshalFloat64Slice(s []float64, bigEndian bool) []byte {
+	b := make([]byte, 0, 8*len(s))
+	for _, x := range s {
+		if bigEndian {
+			b = AppendFloat64BE(b, x)
+		} else {
+			b = AppendFloat64LE(b, x)
+		}
+	}
+	return b
+}
+
+// UnmarshalFloat64Slice is the inverse of MarshalFloat64Slice.
+// It returns ErrLength if len(b) is not a multiple of 8.
+func UnmarshalFloat64Slice(b []byte, bigEndian bool) ([]float64, error) {
+	if len(b)%8 != 0 {
+		return nil, ErrLength
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	s := make([]float64, len(b)/8)
+	for i := range s {
+		s[i] = math.Float64frombits(order.Uint64(b[8*i:]))
+	}
+	return s, nil
+}
+
+// Float64FromBytes returns the float64 of the first 8 bytes of b, in
+// big-endian order if bigEndian is true and otherwise in little-endian
+// order. It is the inverse of AppendFloat64BE and AppendFloat64LE, all bits
+// are kept. It returns ErrShort if len(b) < 8.
+func Float64FromBytes(b []byte, bigEndian bool) (float64, error) {
+	if len(b) < 8 {
+		return 0, ErrShort
+	}
+	if bigEndian {
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+// Float64FromBytesSanitized is Float64FromBytes, but any NaN, signaling or
+// with any sign and payload, is returned as the canonical math.NaN().
+// Untrusted input can't then pass payloads or signaling NaNs on.
+func Float64FromBytesSanitized(b []byte, bigEndian bool) (float64, error) {
+	x, err := Float64FromBytes(b, bigEndian)
+	if x != x {
+		x = math.NaN()
+	}
+	return x, err
+}
@@ -0,0 +1,105 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+var bytesink []byte
+
+func BenchmarkAppendFloat64LE(b *testing.B) {
+	buf := make([]byte, 0, 8)
+	for n := 0; n < b.N; n++ {
+		buf = AppendFloat64LE(buf[:0], float64(n))
+	}
+	bytesink = buf
+}
+func BenchmarkMarshalFloat64Slice(b *testing.B) {
+	s := randomSlice(1000)
+	var buf []byte
+	for n := 0; n < b.N; n++ {
+		buf = MarshalFloat64Slice(s, true)
+	}
+	bytesink = buf
+}
+
+func BenchmarkFloat64FromBytes(b *testing.B) {
+	buf := AppendFloat64LE(nil, 1.5)
+	var x float64
+	for n := 0; n < b.N; n++ {
+		x, _ = Float64FromBytes(buf, false)
+	}
+	fsink = x
+}
+
+// ------------------------------------------------------------- Tests
+func TestMarshalFloat64Slice(t *testing.T) {
+	s := []float64{
+		1, -2.5, 0, math.Copysign(0, -1), 0x1p-1074, math.MaxFloat64,
+		math.Inf(1), math.Inf(-1), math.NaN(),
+		math.Float64frombits(0x7ff0000000000001), math.Float64frombits(0xfff8dead0000beef),
+	}
+	s = append(s, randomSlice(100)...)
+	if b := AppendFloat64BE(nil, 1); b[0] != 0x3f || b[1] != 0xf0 {
+		t.Fatalf("AppendFloat64BE(1) % X", b)
+	}
+	if b := AppendFloat64LE(nil, 1); b[7] != 0x3f || b[6] != 0xf0 {
+		t.Fatalf("AppendFloat64LE(1) % X", b)
+	}
+	for _, big := range []bool{false, true} {
+		b := MarshalFloat64Slice(s, big)
+		if len(b) != 8*len(s) {
+			t.Fatalf("len %d", len(b))
+		}
+		r, err := UnmarshalFloat64Slice(b, big)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range s {
+			if math.Float64bits(r[i]) != math.Float64bits(s[i]) {
+				t.Fatalf("big %v  i %d  %X != %X", big, i, math.Float64bits(r[i]), math.Float64bits(s[i]))
+			}
+		}
+		if _, err := UnmarshalFloat64Slice(b[1:], big); err != ErrLength {
+			t.Fatalf("err %v", err)
+		}
+	}
+}
+
+func TestFloat64FromBytes(t *testing.T) {
+	s := []float64{
+		1, -2.5, 0, math.Copysign(0, -1), 0x1p-1074, math.MaxFloat64,
+		math.Inf(1), math.Inf(-1), math.NaN(),
+		math.Float64frombits(0x7ff0000000000001), math.Float64frombits(0xfff8dead0000beef),
+	}
+	s = append(s, randomSlice(100)...)
+	canonical := math.Float64bits(math.NaN())
+	for _, x := range s {
+		for _, big := range []bool{false, true} {
+			b := AppendFloat64LE(nil, x)
+			if big {
+				b = AppendFloat64BE(nil, x)
+			}
+			y, err := Float64FromBytes(append(b, 0xff), big)      // extra bytes are ignored
+			if err != nil || math.Float64bits(y) != math.Float64bits(x) {
+				t.Fatalf("big %v  %X != %X  %v", big, math.Float64bits(y), math.Float64bits(x), err)
+			}
+			z, err := Float64FromBytesSanitized(b, big)
+			want := math.Float64bits(x)
+			if x != x {
+				want = canonical
+			}
+			if err != nil || math.Float64bits(z) != want {
+				t.Fatalf("sanitized big %v  %X != %X  %v", big, math.Float64bits(z), want, err)
+			}
+		}
+	}
+	for n := 0; n < 8; n++ {
+		if _, err := Float64FromBytes(make([]byte, n), true); err != ErrShort {
+			t.Fatalf("len %d  err %v", n, err)
+		}
+		if _, err := Float64FromBytesSanitized(make([]byte, n), false); err != ErrShort {
+			t.Fatalf("sanitized len %d  err %v", n, err)
+		}
+	}
+}
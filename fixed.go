@@ -0,0 +1,115 @@
+package fbits
+
+import (
+	"math"
+	"math/bits"
+)
+
+// ToFixedQ converts x to a fixed-point integer with fracBits fractional bits,
+// the Q format value v with v * 2^-fracBits = x, rounded to nearest, ties to even.
+//
+// The significand is shifted by the exponent of x plus fracBits in integer
+// arithmetic, so the result is the correctly rounded x * 2^fracBits with
+// one rounding. fracBits can be negative.
+// If the rounded value doesn't fit in int64, ToFixedQ saturates to
+// MaxInt64 or MinInt64 and ok is false.
+// Special cases:
+// ToFixedQ(+/-0, f)     = 0, true
+// ToFixedQ(+Inf, f)     = MaxInt64, false
+// ToFixedQ(-Inf, f)     = MinInt64, false
+// ToFixedQ(NaN, f)      = 0, false
+//
+func ToFixedQ(x float64, fracBits int) (v int64, ok bool) {
+	u := math.Float64bits(x)
+	neg := u&signbit != 0
+	u &^= signbit
+	if u > posInf {
+		return 0, false
+	}
+	m, e := u&(1<<52-1), int(u>>52)
+	if e == 0 {
+		e = 1
+	} else {
+		m |= 1 << 52
+	}
+	var r uint64
+	shift := e - 1075 + fracBits              // x = m * 2^(e-1075)
+	switch {
+	case m == 0:
+		return 0, true
+	case u == posInf || shift > 63 || shift >= 0 && bits.Len64(m)+shift > 64:
+		r = maxUint64
+	case shift >= 0:
+		r = m << uint(shift)
+	case shift >= -54:
+		s := uint(-shift)
+		r = m >> s
+		rem, half := m&(1<<s-1), uint64(1)<<(s-1)
+		if rem > half || rem == half && r&1 == 1 {
+			r++
+		}
+	}
+	if neg {
+		if r > 1<<63 {
+			return math.MinInt64, false
+		}
+		return -int64(r), true                // -int64(1<<63) is MinInt64
+	}
+	if r > math.MaxInt64 {
+		return math.MaxInt64, false
+	}
+	return int64(r), true
+}
+
+// FromFixedQ returns the Q format value v with fracBits fractional bits as
+// a float64, v * 2^-fracBits.
+//
+// The result is exact if v has at most 53 significant bits and the result
+// is not subnormal. Otherwise it is float64(v) scaled by Ldexp, which may
+// round twice.
+//
+func FromFixedQ(v int64, fracBits int) float64 {
+	return math.Ldexp(float64(v), -fracBits)
+}
+
+// Int64ToFloatExact returns float64(v) and true if the conversion is exact.
+//
+// v is exact if its significant bits, from the highest 1 bit to the lowest,
+// fit in the 53 bit significand: abs(v) <= 2^53 or v has enough trailing
+// zero bits, as 2^60 or 3 * 2^58. The smallest inexact positive v is 2^53+1.
+// MinInt64 = -2^63 is exact.
+//
+func Int64ToFloatExact(v int64) (float64, bool) {
+	u := uint64(v)
+	if v < 0 {
+		u = -u
+	}
+	return float64(v), u == 0 || bits.Len64(u)-bits.TrailingZeros64(u) <= 53
+}
+
+// ToUint64Saturating returns x truncated towards zero as an uint64,
+// saturating at 0 and maxUint64.
+//
+// Go's uint64(x) is implementation dependent for x out of range. Here the
+// range is checked from the exponent field: x < 1 gives 0 and x >= 2^64
+// gives maxUint64, otherwise the significand is shifted by the exponent.
+// Special cases:
+// ToUint64Saturating(x < 1)       = 0, also for -Inf and all negative x
+// ToUint64Saturating(x >= 2^64)   = maxUint64, also for +Inf
+// ToUint64Saturating(NaN)         = 0
+//
+func ToUint64Saturating(x float64) uint64 {
+	u := math.Float64bits(x)
+	e := int(u>>52) - 1075                    // x = m * 2^e, sign bit in u>>52
+	switch {
+	case u > posInf || e < -52:                // NaN, negative or x < 1
+		return 0
+	case e >= 64-52:
+		return maxUint64
+	}
+	m := u&(1<<52-1) | 1<<52
+	if e >= 0 {
+		return m << uint(e)
+	}
+	return m >> uint(-e)
+}
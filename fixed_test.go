@@ -0,0 +1,192 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+func BenchmarkToFixedQ(b *testing.B) {
+	var v int64
+	for n := 0; n < b.N; n++ {
+		v, _ = ToFixedQ(float64(n)*0x1p-10, 16)
+	}
+	isink = int(v)
+}
+
+func BenchmarkFromFixedQ(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = FromFixedQ(int64(n), 16)
+	}
+	fsink = y
+}
+
+func BenchmarkInt64ToFloatExact(b *testing.B) {
+	var ok bool
+	for n := 0; n < b.N; n++ {
+		_, ok = Int64ToFloatExact(int64(n) << 30)
+	}
+	bsink = ok
+}
+
+func BenchmarkToUint64Saturating(b *testing.B) {
+	var u uint64
+	for n := 0; n < b.N; n++ {
+		u = ToUint64Saturating(float64(n) * 1e10)
+	}
+	usink = u
+}
+
+// ------------------------------------------------------------- Tests
+func TestToFixedQ(t *testing.T) {
+	const rounds int = 1e7
+	zero, inf := 0.0, math.Inf(1)
+	cases := []struct {
+		x    float64
+		frac int
+		v    int64
+		ok   bool
+	}{
+		{1.5, 1, 3, true},
+		{-1.5, 1, -3, true},
+		{1.25, 1, 2, true},                       // tie to even
+		{1.75, 1, 4, true},
+		{-0.75, 1, -2, true},
+		{0.1, 0, 0, true},
+		{-zero, 8, 0, true},
+		{0x1p-1074, 1074, 1, true},
+		{0x1p-1074, 1073, 0, true},               // tie to even
+		{3 * 0x1p-1074, 1073, 2, true},
+		{1024, -10, 1, true},
+		{1536, -10, 2, true},
+		{0x1p62, 0, 1 << 62, true},
+		{0x1p63, 0, math.MaxInt64, false},
+		{-0x1p63, 0, math.MinInt64, true},
+		{-0x1p63, 1, math.MinInt64, false},
+		{1, 63, math.MaxInt64, false},
+		{-1, 63, math.MinInt64, true},
+		{1, 1000, math.MaxInt64, false},
+		{inf, 0, math.MaxInt64, false},
+		{-inf, 0, math.MinInt64, false},
+		{math.NaN(), 0, 0, false},
+	}
+	for _, c := range cases {
+		v, ok := ToFixedQ(c.x, c.frac)
+		t.Logf("%-22v Q%-5d %21d %v", c.x, c.frac, v, ok)
+		if v != c.v || ok != c.ok {
+			t.Fatalf("ToFixedQ(%v, %d) = %d, %v", c.x, c.frac, v, ok)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		r := Splitmix(&state)
+		frac := int(r%80) - 10
+		v := int64(Splitmix(&state)) >> (11 + r>>8%53)    // at most 53 significant bits
+		x := FromFixedQ(v, frac)
+		w, ok := ToFixedQ(x, frac)
+		if !ok || w != v {
+			t.Logf("v    %d  Q%d", v, frac)
+			t.Logf("x    %v", x)
+			t.Fatalf("w    %d %v", w, ok)
+		}
+		x = RandomFloat64(&state)
+		s := math.Ldexp(x, frac)
+		if math.Abs(s) < 0x1p62 && math.Abs(s) >= 0x1p-1000 {
+			w, ok = ToFixedQ(x, frac)
+			if !ok || w != int64(math.RoundToEven(s)) {
+				t.Logf("x    %v  Q%d", x, frac)
+				t.Fatalf("w    %d %v, want %v", w, ok, math.RoundToEven(s))
+			}
+		}
+	}
+}
+
+func TestInt64ToFloatExact(t *testing.T) {
+	const rounds int = 1e7
+	cases := []struct {
+		v  int64
+		ok bool
+	}{
+		{0, true},
+		{1, true},
+		{-1, true},
+		{1 << 53, true},
+		{1<<53 + 1, false},
+		{-(1<<53 + 1), false},
+		{1<<53 + 2, true},
+		{1 << 60, true},
+		{3 << 58, true},
+		{1<<60 + 1<<8, true},       // 53 significant bits
+		{1<<60 + 1<<7, false},
+		{math.MaxInt64, false},
+		{math.MinInt64, true},
+	}
+	for _, c := range cases {
+		f, ok := Int64ToFloatExact(c.v)
+		t.Logf("%20d  %-24v %v", c.v, f, ok)
+		if ok != c.ok || f != float64(c.v) {
+			t.Fatalf("Int64ToFloatExact(%d) = %v, %v", c.v, f, ok)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		v := int64(Splitmix(&state)) >> (Splitmix(&state) % 64)
+		v &^= 1<<(Splitmix(&state)%16) - 1
+		f, ok := Int64ToFloatExact(v)
+		exact := f < 0x1p63 && int64(f) == v           // float64(v) can round up to 2^63
+		if ok != exact {
+			t.Fatalf("Int64ToFloatExact(%d) = %v, %v", v, f, ok)
+		}
+	}
+}
+
+func TestToUint64Saturating(t *testing.T) {
+	const rounds int = 1e7
+	inf := math.Inf(1)
+	cases := []struct {
+		x    float64
+		want uint64
+	}{
+		{0, 0},
+		{math.Copysign(0, -1), 0},
+		{0.999, 0},
+		{1, 1},
+		{1.999, 1},
+		{-1, 0},
+		{-1e300, 0},
+		{0x1p53 + 2, 1<<53 + 2},
+		{0x1p63, 1 << 63},
+		{NextToZero(0x1p64), 1<<64 - 1<<11},
+		{0x1p64, maxUint64},
+		{1e300, maxUint64},
+		{inf, maxUint64},
+		{-inf, 0},
+		{math.NaN(), 0},
+		{0x1p-1074, 0},
+	}
+	for _, c := range cases {
+		u := ToUint64Saturating(c.x)
+		t.Logf("%-24v %d", c.x, u)
+		if u != c.want {
+			t.Fatalf("ToUint64Saturating(%v) = %d, want %d", c.x, u, c.want)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := math.Ldexp(RandomFloat64(&state), -int(Splitmix(&state)%1100))
+		x = math.Ldexp(x, int(Splitmix(&state)%66)-Log2(x))
+		u := ToUint64Saturating(x)
+		var want uint64
+		switch {
+		case x >= 0x1p64:
+			want = maxUint64
+		case x >= 0x1p63:
+			want = uint64(x-0x1p63) + 1<<63
+		case x >= 1:
+			want = uint64(x)
+		}
+		if u != want {
+			t.Fatalf("ToUint64Saturating(%v) = %d, want %d", x, u, want)
+		}
+	}
+}
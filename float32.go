@@ -0,0 +1,145 @@
+package fbits
+
+import (
+	"math"
+	"unsafe"
+)
+
+// AdjacentFP32 returns true, if x and y are finite and adjacent float32's.
+//
+// This is AdjacentFP for single precision, only floating-point operations
+// are used. As AdjacentFP it is correct at zero:
+// AdjacentFP32(0, -2^-149)        = true
+// AdjacentFP32(-0, 2^-149)        = true
+// AdjacentFP32(-2^-149, 2^-149)   = false
+// AdjacentFP32(+Inf, +MaxFloat32) = false
+// AdjacentFP32(x, NaN)            = false
+// 2^-149 is the smallest nonzero float32.
+//
+// Halving a float32 subnormal is not exact, x/2 loses the lowest bit by
+// rounding to even. For adjacent subnormals this still rounds the mean onto
+// x or y, which is why the zero and subnormal cases work. The method breaks
+// if subnormals are flushed to zero (FTZ/DAZ) or if the compiler fuses a
+// halving multiply x*0.5 into an FMA, as it may do on some architectures.
+// The explicit float32 conversions below prevent the fusing.
+//
+func AdjacentFP32(x, y float32) bool {
+	if x == y {
+		return false
+	}
+	mean := float32(x/2) + float32(y/2)    // this avoids overflowing x + y to Inf
+	if mean != x && mean != y {            // NaNs
+		return false
+	}
+	return -math.MaxFloat32 <= mean && mean <= math.MaxFloat32  // Infs
+}
+
+// Splitmix32 returns the high and low 32 bits of Splitmix(state).
+func Splitmix32(state *uint64) (hi, lo uint32) {
+	u := Splitmix(state)
+	return uint32(u >> 32), uint32(u)
+}
+
+// RandomFloat32 returns a random float32 from [-MaxFloat32, MaxFloat32].
+//
+// The float is built from the high 32 bits of Splitmix(state) as
+// FiniteFloat64frombits does for float64's: for Inf and NaN patterns the
+// exponent (0xff) is replaced by u mod 0xff (0 - 254). The 2^24 remapped
+// patterns, 1/256 of all, double the probability of the floats they map to.
+//
+func RandomFloat32(state *uint64) float32 {
+	u := uint32(Splitmix(state) >> 32)
+	if u&^(1<<31) >= 0x7f800000 {
+		u = u&^0x7f800000 | (u%0xff)<<23
+	}
+	return math.Float32frombits(u)
+}
+
+// Float is the constraint of FloatLess, float32 and float64 types.
+// It is the Float of golang.org/x/exp/constraints.
+type Float interface {
+	~float32 | ~float64
+}
+
+// FloatLess returns true if x is before y in the IEEE 754 total order:
+// -NaN < -Inf < ... < -0 < +0 < ... < +Inf < +NaN.
+//
+// It works for float32 and float64 types, and generic code can sort slices
+// of either with the same placement of NaNs and zeros. The width is chosen
+// by unsafe.Sizeof(x). For a type parameter it is not a constant, but the
+// compiler usually folds it when the function is instantiated for a type,
+// and there is no boxing and no allocation.
+// For float64 it is totalLess.
+//
+func FloatLess[F Float](x, y F) bool {
+	if unsafe.Sizeof(x) == 4 {
+		return totalKey32(math.Float32bits(float32(x))) < totalKey32(math.Float32bits(float32(y)))
+	}
+	return totalLess(float64(x), float64(y))
+}
+
+// totalKey32 maps float32 bits u to an uint32 in the total order of the floats.
+// Negatives are flipped to count down and positives are moved above them.
+func totalKey32(u uint32) uint32 {
+	if u&(1<<31) != 0 {
+		return ^u
+	}
+	return u | 1<<31
+}
+
+// Float32RoundTripUlps returns UlpsBetween(x, float64(float32(x))), the
+// loss of a round trip of x through float32 in float64 ulps.
+//
+// float32(x) rounds to nearest even, so the loss of a normal float32 range
+// x is at most 2^28 ulps, half a float32 ulp. Below the float32 normal range
+// x is rounded to a float32 subnormal or zero and the loss is larger.
+// Special cases:
+// Float32RoundTripUlps(x), x exactly a float32     = 0
+// Float32RoundTripUlps(+/-Inf)                     = 0
+// Float32RoundTripUlps(x), float32(x) = +/-Inf     = maxUint64, x finite
+// Float32RoundTripUlps(NaN)                        = maxUint64
+//
+func Float32RoundTripUlps(x float64) uint64 {
+	y := float64(float32(x))
+	if IsInf(y) && !IsInf(x) {
+		return maxUint64
+	}
+	return UlpsBetween(x, y)
+}
+
+// RandomFloatG returns a random finite float of type F, RandomFloat32 for
+// float32 types and RandomFloat64 for float64 types. A float32 is built from
+// the high 32 bits of Splitmix(state).
+//
+//	x := RandomFloatG[float32](&state)
+//
+func RandomFloatG[F Float](state *uint64) F {
+	if unsafe.Sizeof(F(0)) == 4 {
+		return F(RandomFloat32(state))
+	}
+	return F(RandomFloat64(state))
+}
+
+// SnapToFloat32 returns float64(float32(x)), the float64 nearest to x which
+// is exactly a float32, rounded to nearest even. It is idempotent, and the
+// result survives a float32 round trip unchanged.
+//
+// Go converts with gradual underflow: x below the float32 normal range
+// 2^-126 is rounded to a float32 subnormal, multiples of 2^-149, and
+// abs(x) <= 2^-150 to a zero of the sign of x. Subnormals are not flushed
+// to zero.
+// Special cases:
+// SnapToFloat32(x), abs(x) >= MaxFloat32 + 2^103  = +/-Inf, overflow
+// SnapToFloat32(+/-Inf)                           = +/-Inf
+// SnapToFloat32(NaN)                              = NaN, the payload may change
+//
+func SnapToFloat32(x float64) float64 {
+	return float64(float32(x))
+}
+
+// IsFloat32Representable returns true if x is exactly a float32,
+// SnapToFloat32(x) == x. +/-Inf and the zeros are, NaNs are not.
+// It is true if and only if Float32RoundTripUlps(x) == 0.
+func IsFloat32Representable(x float64) bool {
+	return float64(float32(x)) == x
+}
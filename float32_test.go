@@ -0,0 +1,340 @@
+package fbits
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+var f32sink float32
+
+// ulpsBetween32 is UlpsBetween for float32's, a reference for the tests.
+func ulpsBetween32(x, y float32) uint32 {
+	k := math.Float32bits(x)
+	n := math.Float32bits(y)
+	signdiff := k^n >= 1<<31
+	k &^= 1 << 31
+	n &^= 1 << 31
+	switch {
+	case k > 0x7f800000 || n > 0x7f800000:
+		return math.MaxUint32
+	case signdiff:
+		return n + k
+	case n > k:
+		return n - k
+	}
+	return k - n
+}
+
+func BenchmarkAdjacentFP32(b *testing.B) {
+	var is bool
+	var f2 float32 = 1.0
+	for n := 0; n < b.N; n++ {
+		is = AdjacentFP32(float32(n), f2)
+	}
+	bsink = is
+}
+
+func BenchmarkRandomFloat32(b *testing.B) {
+	var f float32
+	state := uint64(1)
+	for n := 0; n < b.N; n++ {
+		f = RandomFloat32(&state)
+	}
+	f32sink = f
+}
+
+func BenchmarkFloat32RoundTripUlps(b *testing.B) {
+	var u uint64
+	for n := 0; n < b.N; n++ {
+		u = Float32RoundTripUlps(float64(n) * 1.1)
+	}
+	usink = u
+}
+
+func BenchmarkRandomFloatG32(b *testing.B) {
+	var f float32
+	state := uint64(1)
+	for n := 0; n < b.N; n++ {
+		f = RandomFloatG[float32](&state)
+	}
+	f32sink = f
+}
+
+func BenchmarkRandomFloatG64(b *testing.B) {
+	var f float64
+	state := uint64(1)
+	for n := 0; n < b.N; n++ {
+		f = RandomFloatG[float64](&state)
+	}
+	fsink = f
+}
+
+func BenchmarkIsFloat32Representable(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is = IsFloat32Representable(float64(n) * 0.1)
+	}
+	bsink = is
+}
+
+// ------------------------------------------------------------- Tests
+func TestAdjacentFP32(t *testing.T) {
+	const rounds int = 1e7
+	var zero, min, max float32 = 0, 0x1p-149, math.MaxFloat32
+	inf, nan := float32(math.Inf(1)), float32(math.NaN())
+
+	t.Logf("zero min    %v", AdjacentFP32(zero, min))
+	t.Logf("-zero -min  %v", AdjacentFP32(-zero, -min))
+	t.Logf("-zero min   %v", AdjacentFP32(-zero, min))
+	t.Logf("zero -min   %v", AdjacentFP32(zero, -min))
+	t.Logf("-min min    %v", AdjacentFP32(-min, min))
+	t.Logf("zero -zero  %v", AdjacentFP32(zero, -zero))
+	t.Logf("max inf     %v", AdjacentFP32(max, inf))
+	t.Logf("NaN inf     %v", AdjacentFP32(nan, inf))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		u := uint32(Splitmix(&state))
+		if i&7 == 2 {
+			u &^= 0x7f800000                 // subnormal
+		}
+		f1 := math.Float32frombits(u)
+		if f1 != f1 || f1 > max || f1 < -max {
+			continue
+		}
+		f2 := math.Nextafter32(f1, 0)
+		if i&15 == 0 {
+			f2 *= 2
+		}
+		if i&255 == 1 {
+			f2 = -f2
+		}
+		Ulps := ulpsBetween32(f1, f2)
+		if AdjacentFP32(f1, f2) != (Ulps == 1) {
+			t.Logf("Ulps %v", Ulps)
+			t.Logf("i    %d", i)
+			t.Logf("F1   %v", f1)
+			t.Fatalf("F2   %v", f2)
+		}
+	}
+}
+
+func TestRandomFloat32(t *testing.T) {
+	const rounds int = 1e7
+	state := uint64(1)
+	saved := state
+	hi, lo := Splitmix32(&state)
+	state = saved
+	if u := Splitmix(&state); hi != uint32(u>>32) || lo != uint32(u) {
+		t.Fatalf("Splitmix32 %X %X", hi, lo)
+	}
+	var subnormals, negatives, zeros int
+	for i := 0; i < rounds; i++ {
+		f := RandomFloat32(&state)
+		if f != f || f < -math.MaxFloat32 || f > math.MaxFloat32 {
+			t.Fatalf("i %d: %v", i, f)
+		}
+		switch {
+		case f == 0:
+			zeros++
+		case -0x1p-126 < f && f < 0x1p-126:
+			subnormals++
+		}
+		if math.Signbit(float64(f)) {
+			negatives++
+		}
+	}
+	t.Logf("subnormals %d, zeros %d, negatives %d", subnormals, zeros, negatives)
+	// Subnormals are 2^24 patterns out of 2^32, doubled by the remap for 1/255 of them.
+	if p := float64(subnormals) / float64(rounds); math.Abs(p-0x1p-8) > 0x1p-11 {
+		t.Fatalf("subnormal fraction %v", p)
+	}
+	if math.Abs(float64(negatives)/float64(rounds)-0.5) > 0.001 {
+		t.Fatalf("negative fraction %v", float64(negatives)/float64(rounds))
+	}
+}
+
+func TestFloatLess(t *testing.T) {
+	nan32 := func(u uint32) float32 { return math.Float32frombits(u) }
+	inf32 := float32(math.Inf(1))
+	want32 := []float32{
+		nan32(0xffc00001), nan32(0xff800001), -inf32, -math.MaxFloat32, -1, -0x1p-149,
+		float32(math.Copysign(0, -1)), 0, 0x1p-149, 1, math.MaxFloat32, inf32,
+		nan32(0x7f800001), nan32(0x7fc00001),
+	}
+	nan64 := func(u uint64) float64 { return math.Float64frombits(u) }
+	inf64 := math.Inf(1)
+	want64 := []float64{
+		nan64(0xfff8000000000001), nan64(0xfff0000000000001), -inf64, -math.MaxFloat64, -1,
+		-0x1p-1074, math.Copysign(0, -1), 0, 0x1p-1074, 1, math.MaxFloat64, inf64,
+		nan64(0x7ff0000000000001), nan64(0x7ff8000000000001),
+	}
+	state := uint64(1)
+	s32 := append([]float32(nil), want32...)
+	s64 := append([]float64(nil), want64...)
+	for i := len(s32) - 1; i > 0; i-- {
+		j := int(Splitmix(&state) % uint64(i+1))
+		s32[i], s32[j] = s32[j], s32[i]
+		s64[i], s64[j] = s64[j], s64[i]
+	}
+	sort.Slice(s32, func(i, j int) bool { return FloatLess(s32[i], s32[j]) })
+	sort.Slice(s64, func(i, j int) bool { return FloatLess(s64[i], s64[j]) })
+	for i := range want32 {
+		if math.Float32bits(s32[i]) != math.Float32bits(want32[i]) {
+			t.Fatalf("float32 %d: %X, want %X", i, math.Float32bits(s32[i]), math.Float32bits(want32[i]))
+		}
+		if math.Float64bits(s64[i]) != math.Float64bits(want64[i]) {
+			t.Fatalf("float64 %d: %X, want %X", i, math.Float64bits(s64[i]), math.Float64bits(want64[i]))
+		}
+	}
+	type celsius float32
+	if !FloatLess(celsius(-1), celsius(0)) || FloatLess(celsius(0), celsius(0)) {
+		t.Fatalf("named float32 type")
+	}
+	const rounds int = 1e6
+	for i := 0; i < rounds; i++ {
+		x, y := RandomFloat32(&state), RandomFloat32(&state)
+		if x != y && FloatLess(x, y) != (x < y) {
+			t.Fatalf("FloatLess(%v, %v)", x, y)
+		}
+		a, b := RandomFloat64(&state), RandomFloat64(&state)
+		if a != b && FloatLess(a, b) != (a < b) {
+			t.Fatalf("FloatLess(%v, %v)", a, b)
+		}
+	}
+	if n := testing.AllocsPerRun(100, func() { bsink = FloatLess(float32(1), 2) }); n != 0 {
+		t.Fatalf("allocs %v", n)
+	}
+}
+
+func TestFloat32RoundTripUlps(t *testing.T) {
+	const rounds int = 1e6
+	inf := math.Inf(1)
+	cases := []struct {
+		x    float64
+		want uint64
+	}{
+		{1, 0},
+		{-0.5, 0},
+		{0x1p-149, 0},
+		{math.MaxFloat32, 0},
+		{1 + 0x1p-52, 1},
+		{1 + 0x1p-29, 1 << 23},                 // float32 ulp of 1 is 2^-23
+		{1 + 0x1p-24, 1 << 28},                 // a tie, rounds to 1
+		{1 + 0x1p-24 + 0x1p-52, 1<<28 - 1},     // rounds up to 1 + 2^-23
+		{0x1p-151, UlpsBetween(0x1p-151, 0)},
+		{math.Copysign(0, -1), 0},
+		{inf, 0},
+		{-inf, 0},
+		{1e39, maxUint64},
+		{math.MaxFloat64, maxUint64},
+		{math.NaN(), maxUint64},
+	}
+	for _, c := range cases {
+		u := Float32RoundTripUlps(c.x)
+		t.Logf("%-24v %d", c.x, u)
+		if u != c.want {
+			t.Fatalf("Float32RoundTripUlps(%v) = %d, want %d", c.x, u, c.want)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		f := RandomFloat32(&state)
+		x := float64(f)
+		if u := Float32RoundTripUlps(x); u != 0 {
+			t.Fatalf("Float32RoundTripUlps(%v) = %d", x, u)
+		}
+		if math.Abs(x) < 0x1p-126 || math.Abs(x) >= math.MaxFloat32 {
+			continue
+		}
+		// Between x and the next float32 the loss is at most 2^28.
+		y := math.Float64frombits(math.Float64bits(x) + Splitmix(&state)%(1<<29))
+		if u := Float32RoundTripUlps(y); u > 1<<28 {
+			t.Fatalf("Float32RoundTripUlps(%v) = %d", y, u)
+		}
+	}
+}
+
+func TestRandomFloatG(t *testing.T) {
+	const rounds int = 1e6
+	s1, s2 := uint64(1), uint64(1)
+	neg := 0
+	for i := 0; i < rounds; i++ {
+		f := RandomFloatG[float32](&s1)
+		if f != RandomFloat32(&s2) || f != f || f > math.MaxFloat32 || f < -math.MaxFloat32 {
+			t.Fatalf("RandomFloatG[float32] = %v", f)
+		}
+		if f < 0 {
+			neg++
+		}
+	}
+	t.Logf("float32 negative %d of %d", neg, rounds)
+	s1, s2, neg = 1, 1, 0
+	for i := 0; i < rounds; i++ {
+		x := RandomFloatG[float64](&s1)
+		if x != RandomFloat64(&s2) || !IsFinite(x) {
+			t.Fatalf("RandomFloatG[float64] = %v", x)
+		}
+		if x < 0 {
+			neg++
+		}
+	}
+	t.Logf("float64 negative %d of %d", neg, rounds)
+	type meters float32
+	var m meters = RandomFloatG[meters](&s1)
+	if m != m {
+		t.Fatalf("RandomFloatG[meters] = %v", m)
+	}
+}
+
+func TestSnapToFloat32(t *testing.T) {
+	const rounds int = 1e6
+	inf := math.Inf(1)
+	cases := []struct {
+		x, snap float64
+		is      bool
+	}{
+		{1, 1, true},
+		{0.1, float64(float32(0.1)), false},
+		{1 + 0x1p-24, 1, false},                     // a tie, to even
+		{1 + 0x1p-24 + 0x1p-52, 1 + 0x1p-23, false},
+		{0x1p-149, 0x1p-149, true},
+		{0x1p-150, 0, false},                        // a tie, to zero
+		{0x1.8p-150, 0x1p-149, false},
+		{-0x1p-200, math.Copysign(0, -1), false},
+		{math.MaxFloat32, math.MaxFloat32, true},
+		{math.MaxFloat32 + 0x1p103, inf, false},     // the float32 overflow threshold
+		{math.MaxFloat32 + 0x1p102, math.MaxFloat32, false},
+		{-1e300, -inf, false},
+		{inf, inf, true},
+		{-inf, -inf, true},
+		{math.Copysign(0, -1), math.Copysign(0, -1), true},
+	}
+	for _, c := range cases {
+		y, is := SnapToFloat32(c.x), IsFloat32Representable(c.x)
+		t.Logf("%-24v %-24v %v", c.x, y, is)
+		if math.Float64bits(y) != math.Float64bits(c.snap) || is != c.is {
+			t.Fatalf("SnapToFloat32(%v) = %v, want %v; IsFloat32Representable %v", c.x, y, c.snap, is)
+		}
+	}
+	if y := SnapToFloat32(math.NaN()); y == y || IsFloat32Representable(math.NaN()) {
+		t.Fatalf("NaN: %v", y)
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		if i&1 == 0 {
+			x = math.Ldexp(x, -Log2(x)+int(Splitmix(&state)%300)-150)
+		}
+		y := SnapToFloat32(x)
+		if math.Float64bits(SnapToFloat32(y)) != math.Float64bits(y) || !IsFloat32Representable(y) {
+			t.Fatalf("SnapToFloat32(%v) = %v is not idempotent", x, y)
+		}
+		if IsFloat32Representable(x) != (Float32RoundTripUlps(x) == 0) {
+			t.Fatalf("IsFloat32Representable(%v) = %v", x, IsFloat32Representable(x))
+		}
+		if IsFloat32Representable(x) != (y == x) {
+			t.Fatalf("IsFloat32Representable(%v) = %v, snap %v", x, IsFloat32Representable(x), y)
+		}
+	}
+}
@@ -160,6 +160,31 @@ func Log2(x float64) int {
 	return exp - 1023                    // x is normal, Inf or NaN
 }
 
+// BiasedExponent returns the raw 11 bit exponent field of x, 0..2047.
+// 
+// The field is 0 for zeros and subnormals and 2047 for Inf and NaN.
+// Unlike Log2, it doesn't look at the significand of a subnormal.
+// Special cases:
+// BiasedExponent(+/-0)         = 0
+// BiasedExponent(2^-1074)      = 0
+// BiasedExponent(1)            = 1023
+// BiasedExponent(MaxFloat64)   = 2046
+// BiasedExponent(+/-Inf, NaN)  = 2047
+// 
+func BiasedExponent(x float64) uint {
+	return uint(math.Float64bits(x) &^ signbit >> 52)
+}
+
+// UnbiasedExponent returns BiasedExponent(x) - 1023.
+// 
+// For normal floats it is the same as Log2(x). Zeros and subnormals
+// have -1023 and Inf and NaN 1024, as the field values 0 and 2047.
+// Note the exponent of the subnormal encoding is -1022, not -1023.
+// 
+func UnbiasedExponent(x float64) int {
+	return int(math.Float64bits(x) &^ signbit >> 52) - 1023
+}
+
 // IsPowerOfTwo returns true if float64 x is an integer power of two.
 // 
 // Cases of interest:
@@ -258,11 +283,36 @@ func IsInf(x float64) bool {
 	return math.Float64bits(x) &^ signbit == posInf 
 }
 
+// IsNaN returns true if x is a NaN.
+// 
+// IsNaN is equivalent to math.IsNaN, which uses x != x. This is branch-free
+// and doesn't depend on the floating-point compare of NaNs.
+// 
+func IsNaN(x float64) bool {
+	return math.Float64bits(x) &^ signbit > posInf
+}
+
 // IsFinite returns true if x is not +/-Inf or NaN.
 func IsFinite(x float64) bool {
 	return math.Float64bits(x) &^ signbit < posInf 
 }
 
+// Sign returns -1 if x < 0, +1 if x > 0 and 0 otherwise.
+//
+// Signed zeros have no sign here and NaNs are not ordered.
+// Special cases:
+// Sign(+/-0)    = 0
+// Sign(+/-Inf)  = +/-1
+// Sign(NaN)     = 0
+//
+func Sign(x float64) int {
+	u := math.Float64bits(x)
+	if (u &^ signbit) - 1 >= posInf {    // +/-0 wraps around, NaNs are above posInf - 1
+		return 0
+	}
+	return int(1 | int64(u) >> 63)       // int64(u) >> 63 is -1 for negative x, 0 otherwise
+}
+
 // NextToZero returns the next float64 after x towards zero.
 // 
 // NextToZero(x) is equivalent to math.Nextafter(x, 0).
@@ -321,6 +371,126 @@ func NextFromZeroFP(x float64) float64 {
 	return x + x * 0x1.25p-53            // Inf + Inf = Inf
 }
 
+// NextLargerMagnitude returns the float64 with the next larger absolute
+// value than x and the same sign as x, so NextLargerMagnitude(-1) is
+// -NextFromZero(1). Unlike a successor in total order, it moves negative
+// x towards -Inf. It is NextFromZero done by a single increment of the bits.
+// Special cases:
+// NextLargerMagnitude(NaN)           = NaN
+// NextLargerMagnitude(+/-Inf)        = +/-Inf
+// NextLargerMagnitude(+/-MaxFloat64) = +/-Inf
+// NextLargerMagnitude(0)             = 2^-1074
+// NextLargerMagnitude(-0)            = -2^-1074
+//
+func NextLargerMagnitude(x float64) float64 {
+	u := math.Float64bits(x)
+	if u&^signbit >= posInf {               // +/-Inf and NaN
+		return x
+	}
+	return math.Float64frombits(u + 1)
+}
+
+// NextToward returns the next float64 after x towards y.
+//
+// NextToward(x, y) is equivalent to math.Nextafter(x, y), but it uses
+// NextToZero and NextFromZero.
+// Special cases:
+// NextToward(x, x)       = x
+// NextToward(+/-0, y)    = Copysign(2^-1074, y)     y != 0
+// NextToward(x, NaN)     = NaN
+// NextToward(NaN, y)     = NaN
+//
+func NextToward(x, y float64) float64 {
+	switch {
+	case x != x || y != y:
+		return x + y                     // NaN
+	case x == y:
+		return x
+	case x == 0:
+		return math.Copysign(0x1p-1074, y)
+	case (y > x) == (x > 0):
+		return NextFromZero(x)
+	}
+	return NextToZero(x)
+}
+
+// Half returns x/2 by decrementing the exponent field.
+//
+// Half(x) == x/2 for all x. For abs(x) < 2^-1021 the result is subnormal
+// and the significand is shifted right instead. An odd significand loses
+// its last bit, which is rounded to even as x/2 does.
+// Special cases:
+// Half(+/-Inf)     = +/-Inf
+// Half(NaN)        = NaN
+// Half(+/-0)       = +/-0
+// Half(2^-1074)    = 0
+// Half(3x2^-1074)  = 2^-1073
+//
+func Half(x float64) float64 {
+	u := math.Float64bits(x)
+	a := u &^ signbit
+	exp := a >> 52
+	switch {
+	case exp == 0x7ff:                   // Infs and NaNs
+	case exp > 1:
+		u -= 1 << 52
+	default:                             // subnormal result, a is the significand
+		h := a >> 1                      // in units of 2^-1074
+		if a & h & 1 == 1 {              // halfway and h is odd, round to even
+			h++
+		}
+		u = u & signbit | h
+	}
+	return math.Float64frombits(u)
+}
+
+// Double returns 2x by incrementing the exponent field.
+//
+// Double(x) == x*2 for all x. Subnormals are shifted left, which moves
+// them into the normal range correctly. Double saturates to +/-Inf.
+// Special cases:
+// Double(+/-MaxFloat64) = +/-Inf
+// Double(+/-Inf)        = +/-Inf
+// Double(NaN)           = NaN
+// Double(+/-0)          = +/-0
+//
+func Double(x float64) float64 {
+	u := math.Float64bits(x)
+	a := u &^ signbit
+	exp := a >> 52
+	switch {
+	case exp == 0x7ff:                   // Infs and NaNs
+	case exp == 0x7fe:
+		u = u & signbit | posInf
+	case exp == 0:                       // a << 1 carries into the exponent field
+		u = u & signbit | a << 1         // if the result is normal
+	default:
+		u += 1 << 52
+	}
+	return math.Float64frombits(u)
+}
+
+// MulPow2Checked returns x * 2^n as math.Ldexp(x, n) and true if the
+// scaling was exact.
+// 
+// The scaling is inexact if the result overflows to +/-Inf or if it is
+// subnormal and bits of x were shifted out. Scaling the result back
+// with Ldexp(r, -n) gives x if and only if no bits were lost.
+// Special cases:
+// MulPow2Checked(+/-0, n)     = +/-0, true
+// MulPow2Checked(+/-Inf, n)   = +/-Inf, true
+// MulPow2Checked(NaN, n)      = NaN, true
+// MulPow2Checked(2^-1074, -1) = 0, false
+// MulPow2Checked(1, 1024)     = +Inf, false
+// 
+func MulPow2Checked(x float64, n int) (float64, bool) {
+	r := math.Ldexp(x, n)
+	if !IsFinite(x) || x == 0 {
+		return r, true
+	}
+	return r, !IsInf(r) && math.Ldexp(r, -n) == x
+}
+
 // RandomFloat64 returns a random float64 from [-MaxFloat64, MaxFloat64].
 // Every float has an equal probability 1 / (2^64 - 2^53) ~ 2^-63.999.
 // 
@@ -338,6 +508,31 @@ func FiniteFloat64frombits(u uint64) float64 {
 	return math.Float64frombits(u)
 }
 
+// FiniteFloat64tobits is the inverse of FiniteFloat64frombits for the u's
+// which are not Inf or NaN patterns, ie. u &^ signbit < 0x7ff0000000000000.
+//
+// It returns math.Float64bits(x). The remap in FiniteFloat64frombits is lossy:
+// the 2^53 Inf and NaN patterns (1/2048 of all u's) are mapped onto floats
+// which are also produced by a finite pattern, and for those floats
+// FiniteFloat64tobits returns the finite pattern, not the Inf/NaN one.
+// FiniteFloat64frombits(FiniteFloat64tobits(x)) == x for all finite x.
+//
+func FiniteFloat64tobits(x float64) uint64 {
+	return math.Float64bits(x)
+}
+
+// RemapProbability returns the probability that FiniteFloat64frombits(u)
+// remaps u for a uniformly random u, 2^53 / 2^64 = 1/2048.
+//
+// The remapped patterns are the 2 x 2^52 Infs and NaNs (exponent 0x7ff).
+// Each of them is mapped onto a finite float with the same sign and
+// significand, which is then produced by two patterns. This doubled
+// probability of 1/2048 of the floats is the bias of RandomFloat64.
+//
+func RemapProbability() float64 {
+	return 0x1p53 / 0x1p64
+}
+
 // RandomFloat64RS uses resampling in the case of Inf or Nan.
 // This gives a provable unbiased distribution of floats assuming that the
 // random  number generator Splitmix gives unbiased uniform distribution 
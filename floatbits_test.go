@@ -95,6 +95,14 @@ func BenchmarkLog2(b *testing.B) {
 	isink = u
 }
 
+func BenchmarkBiasedExponent(b *testing.B) {
+	var u uint
+	for n := 0; n < b.N; n++ {
+		u = BiasedExponent(float64(n))
+	}
+	isink = int(u)
+}
+
 func BenchmarkNextToZero(b *testing.B) {
 	var y float64
 	for n := 0; n < b.N; n++ {
@@ -118,6 +126,20 @@ func BenchmarkNextFromZero(b *testing.B) {
 	}
 	fsink = y
 }
+func BenchmarkNextLargerMagnitude(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = NextLargerMagnitude(float64(n))
+	}
+	fsink = y
+}
+func BenchmarkNextToward(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = NextToward(float64(n), 1000)
+	}
+	fsink = y
+}
 func BenchmarkMathNextafter(b *testing.B) {
 	var y float64
 	for n := 0; n < b.N; n++ {
@@ -135,6 +157,66 @@ func BenchmarkRandomFloat64(b *testing.B) {
 	}
 	fsink = y
 }
+
+func signCmp(x float64) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+func BenchmarkSign(b *testing.B) {
+	var s int
+	for n := 0; n < b.N; n++ {
+		s += Sign(float64(n&255 - 128))
+	}
+	isink = s
+}
+func BenchmarkSignCmp(b *testing.B) {
+	var s int
+	for n := 0; n < b.N; n++ {
+		s += signCmp(float64(n&255 - 128))
+	}
+	isink = s
+}
+func BenchmarkHalf(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = Half(float64(n))
+	}
+	fsink = y
+}
+func BenchmarkDouble(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = Double(float64(n))
+	}
+	fsink = y
+}
+func BenchmarkIsNaN(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is = IsNaN(float64(n))
+	}
+	bsink = is
+}
+func BenchmarkMathIsNaN(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is = math.IsNaN(float64(n))
+	}
+	bsink = is
+}
+func BenchmarkMulPow2Checked(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y, _ = MulPow2Checked(float64(n), -1040)
+	}
+	fsink = y
+}
 // ------------------------------------------------------------- Tests
 func TestRandomFloat64(t *testing.T) {
 	const rounds int = 1e8*2
@@ -174,6 +256,22 @@ func TestIsInf(t *testing.T) {
 	t.Logf("NaN            %v", IsInf(math.NaN()))
 	t.Logf("MaxFloat64     %v", IsInf(math.MaxFloat64))
 }
+func TestIsNaN(t *testing.T) {
+	t.Logf("+Inf           %v", IsNaN(math.Inf(1)))
+	t.Logf("-Inf           %v", IsNaN(math.Inf(-1)))
+	t.Logf("NaN            %v", IsNaN(math.NaN()))
+	t.Logf("MaxFloat64     %v", IsNaN(math.MaxFloat64))
+	state := uint64(1)
+	for i := 0; i < 1e7; i++ {
+		f := math.Float64frombits(Splitmix(&state) | posInf)     // Inf or NaN
+		if i & 1 == 0 {
+			f = math.Float64frombits(Splitmix(&state))
+		}
+		if IsNaN(f) != math.IsNaN(f) {
+			t.Fatalf("F    %X", math.Float64bits(f))
+		}
+	}
+}
 func TestIsFinite(t *testing.T) {
 	t.Logf("+Inf           %v", IsFinite(math.Inf(1)))
 	t.Logf("-Inf           %v", IsFinite(math.Inf(-1)))
@@ -539,3 +637,275 @@ func TestIsPowerOfTwo(t *testing.T) {
 		}
 	}
 }
+
+func TestSign(t *testing.T) {
+	const rounds int = 1e7
+	zero, inf, nan := 0.0, math.Inf(1), math.NaN()
+	t.Logf("zero     %v", Sign(zero))
+	t.Logf("-zero    %v", Sign(-zero))
+	t.Logf("+inf     %v", Sign(inf))
+	t.Logf("-inf     %v", Sign(-inf))
+	t.Logf("NaN      %v", Sign(nan))
+	t.Logf("-NaN     %v", Sign(math.Copysign(nan, -1)))
+	t.Logf("-min     %v", Sign(-0x1p-1074))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		f := math.Float64frombits(Splitmix(&state))
+		if i == 0 {
+			f = -zero
+		}
+		if Sign(f) != signCmp(f) {
+			t.Logf("i    %d", i)
+			t.Logf("F    %v", f)
+			t.Fatalf("F    %X", math.Float64bits(f))
+		}
+	}
+}
+
+func TestFiniteFloat64tobits(t *testing.T) {
+	const rounds int = 1e7
+	remapped := 0
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		u := Splitmix(&state)
+		if i&1023 == 0 {
+			u |= posInf                  // force some remaps
+		}
+		x := FiniteFloat64frombits(u)
+		if FiniteFloat64frombits(FiniteFloat64tobits(x)) != x {
+			t.Logf("i    %d", i)
+			t.Fatalf("U    %X", u)
+		}
+		if u &^ signbit >= posInf {
+			remapped++
+			continue
+		}
+		if FiniteFloat64tobits(x) != u {
+			t.Logf("i    %d", i)
+			t.Fatalf("U    %X", u)
+		}
+	}
+	t.Logf("remapped  %d", remapped)
+}
+
+func TestHalfDouble(t *testing.T) {
+	const rounds int = 1e7
+	zero, max, inf, min := 0.0, math.MaxFloat64, math.Inf(1), 0x1p-1074
+	t.Logf("Half min       %v", Half(min))
+	t.Logf("Half 3min      %v", Half(3*min))
+	t.Logf("Half -zero     %v", Half(-zero))
+	t.Logf("Double max     %v", Double(max))
+	t.Logf("Double -max    %v", Double(-max))
+	t.Logf("Double -inf    %v", Double(-inf))
+	t.Logf("Half NaN       %v", Half(math.NaN()))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		u := Splitmix(&state)
+		switch i & 3 {
+		case 0:
+			u &^= posInf                 // subnormal
+		case 1:
+			u = u &^ posInf | 1 << 52    // smallest normal binade
+		}
+		f := math.Float64frombits(u)
+		h, d := Half(f), Double(f)
+		if math.Float64bits(h) != math.Float64bits(f/2) && f == f {
+			t.Logf("i    %d", i)
+			t.Logf("F    %X", u)
+			t.Fatalf("Half %X", math.Float64bits(h))
+		}
+		if math.Float64bits(d) != math.Float64bits(f*2) && f == f {
+			t.Logf("i    %d", i)
+			t.Logf("F    %X", u)
+			t.Fatalf("Double %X", math.Float64bits(d))
+		}
+	}
+}
+
+func TestNextToward(t *testing.T) {
+	const rounds int = 1e7
+	zero, max, inf, nan, min := 0.0, math.MaxFloat64, math.Inf(1), math.NaN(), 0x1p-1074
+	t.Logf("zero -1      %v", NextToward(zero, -1))
+	t.Logf("-zero 1      %v", NextToward(-zero, 1))
+	t.Logf("min -1       %v", NextToward(min, -1))
+	t.Logf("-min 1       %v", NextToward(-min, 1))
+	t.Logf("max inf      %v", NextToward(max, inf))
+	t.Logf("-inf 0       %v", NextToward(-inf, 0))
+	t.Logf("1 1          %v", NextToward(1, 1))
+	t.Logf("1 NaN        %v", NextToward(1, nan))
+	state := uint64(1)
+	specials := []float64{zero, -zero, min, -min, max, -max, inf, -inf, nan, 1, -1}
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		y := RandomFloat64(&state)
+		switch i & 7 {
+		case 0:
+			x = specials[Splitmix(&state) % uint64(len(specials))]
+		case 1:
+			y = specials[Splitmix(&state) % uint64(len(specials))]
+		case 2:
+			y = x
+		}
+		f1 := NextToward(x, y)
+		f2 := math.Nextafter(x, y)
+		if math.Float64bits(f1) != math.Float64bits(f2) && !(f1 != f1 && f2 != f2) {
+			t.Logf("i    %d", i)
+			t.Logf("x    %v", x)
+			t.Logf("y    %v", y)
+			t.Logf("F1   %v", f1)
+			t.Fatalf("F2   %v", f2)
+		}
+	}
+}
+
+func TestRemapProbability(t *testing.T) {
+	const rounds int = 1e8
+	remapped := 0
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		u := Splitmix(&state)
+		if math.Float64bits(FiniteFloat64frombits(u)) != u {
+			remapped++
+		}
+	}
+	p := float64(remapped) / float64(rounds)
+	sd := math.Sqrt(RemapProbability() * (1 - RemapProbability()) / float64(rounds))
+	t.Logf("RemapProbability  %v", RemapProbability())
+	t.Logf("measured          %v (%.2f sd)", p, (p - RemapProbability()) / sd)
+	if abs(p - RemapProbability()) > 5*sd {
+		t.Fatalf("remap rate %v, want %v", p, RemapProbability())
+	}
+}
+
+func TestMulPow2Checked(t *testing.T) {
+	const rounds int = 1e7
+	cases := []struct {
+		x     float64
+		n     int
+		r     float64
+		exact bool
+	}{
+		{1, 10, 1024, true},
+		{3, -1074, 0x1.8p-1073, true},
+		{3, -1075, 0x1p-1073, false},           // 1.5 x 2^-1074 rounds to even
+		{1, -1074, 0x1p-1074, true},
+		{0x1p-1074, 1, 0x1p-1073, true},
+		{0x1p-1074, -1, 0, false},
+		{math.MaxFloat64, 1, math.Inf(1), false},
+		{1, 1024, math.Inf(1), false},
+		{1, 1023, 0x1p1023, true},
+		{math.Inf(-1), -5, math.Inf(-1), true},
+		{0, 5000, 0, true},
+	}
+	for _, c := range cases {
+		r, e := MulPow2Checked(c.x, c.n)
+		t.Logf("%-24v %-6d %-24v %v", c.x, c.n, r, e)
+		if r != c.r || e != c.exact {
+			t.Fatalf("MulPow2Checked(%v, %d) = %v, %v", c.x, c.n, r, e)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		n := int(Splitmix(&state) % 2200) - 1100
+		r, e := MulPow2Checked(x, n)
+		// exact iff the result is finite and the lowest 1-bit of x stays >= 2^-1074
+		want := IsFinite(r) && LogUlp(x) + bitsTrailingZeros(x) + n >= -1074
+		if e != want {
+			t.Logf("i    %d", i)
+			t.Logf("x    %v", x)
+			t.Fatalf("n    %d  %v", n, e)
+		}
+	}
+}
+
+// bitsTrailingZeros returns the number of trailing zero bits of the
+// significand of nonzero finite x, including the implicit bit.
+func bitsTrailingZeros(x float64) int {
+	u := math.Float64bits(x) & (1<<52 - 1)
+	if math.Float64bits(x) &^ signbit >= 1<<52 {
+		u |= 1 << 52
+	}
+	n := 0
+	for u & 1 == 0 {
+		u >>= 1
+		n++
+	}
+	return n
+}
+
+func TestBiasedExponent(t *testing.T) {
+	const rounds int = 1e7
+	zero, inf := 0.0, math.Inf(1)
+	cases := []struct {
+		x     float64
+		field uint
+	}{
+		{zero, 0},
+		{-zero, 0},
+		{0x1p-1074, 0},
+		{-0x1p-1022 + 0x1p-1074, 0},
+		{0x1p-1022, 1},
+		{1, 1023},
+		{-1.5, 1023},
+		{math.MaxFloat64, 2046},
+		{inf, 2047},
+		{-inf, 2047},
+		{math.NaN(), 2047},
+	}
+	for _, c := range cases {
+		t.Logf("%-24v %4d %5d", c.x, BiasedExponent(c.x), UnbiasedExponent(c.x))
+		if BiasedExponent(c.x) != c.field || UnbiasedExponent(c.x) != int(c.field)-1023 {
+			t.Fatalf("BiasedExponent(%v) = %d", c.x, BiasedExponent(c.x))
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		if BiasedExponent(x) == 0 || BiasedExponent(x) == 2047 {
+			continue
+		}
+		if UnbiasedExponent(x) != Log2(x) {
+			t.Logf("x    %v", x)
+			t.Fatalf("UnbiasedExponent %d, Log2 %d", UnbiasedExponent(x), Log2(x))
+		}
+	}
+}
+
+func TestNextLargerMagnitude(t *testing.T) {
+	const rounds int = 1e7
+	inf := math.Inf(1)
+	cases := []struct{ x, want float64 }{
+		{0, 0x1p-1074},
+		{math.Copysign(0, -1), -0x1p-1074},
+		{1, 1 + 0x1p-52},
+		{-1, -1 - 0x1p-52},
+		{math.MaxFloat64, inf},
+		{-math.MaxFloat64, -inf},
+		{inf, inf},
+		{-inf, -inf},
+	}
+	for _, c := range cases {
+		y := NextLargerMagnitude(c.x)
+		t.Logf("%-24v %v", c.x, y)
+		if y != c.want {
+			t.Fatalf("NextLargerMagnitude(%v) = %v, want %v", c.x, y, c.want)
+		}
+	}
+	if y := NextLargerMagnitude(math.NaN()); y == y {
+		t.Fatalf("NextLargerMagnitude(NaN) = %v", y)
+	}
+	if NextLargerMagnitude(-1) != -NextFromZero(1) {
+		t.Fatalf("NextLargerMagnitude(-1) != -NextFromZero(1)")
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		y := NextLargerMagnitude(x)
+		if math.Signbit(x) != math.Signbit(y) || UlpsBetween(math.Abs(x), math.Abs(y)) != 1 ||
+			!(math.Abs(y) > math.Abs(x)) || y != NextFromZero(x) {
+			t.Logf("x  %X", math.Float64bits(x))
+			t.Fatalf("NextLargerMagnitude(%v) = %v", x, y)
+		}
+	}
+}
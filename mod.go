@@ -0,0 +1,122 @@
+package fbits
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Remainder returns the IEEE 754 remainder of x/y, x - n*y where n is x/y
+// rounded to the nearest integer, ties to even. It is math.Remainder
+// with a faster reduction.
+//
+// The result is exact. The reduction is done by modBits on the significands
+// as integers, 64 bits of the exponent difference per step, where
+// math.Remainder subtracts scaled y's one exponent step at a time.
+// Special cases are as for math.Remainder:
+// Remainder(+/-Inf, y)  = NaN
+// Remainder(NaN, y)     = NaN
+// Remainder(x, 0)       = NaN
+// Remainder(x, +/-Inf)  = x
+// Remainder(x, NaN)     = NaN
+// A zero result has the sign of x.
+//
+func Remainder(x, y float64) float64 {
+	switch {
+	case x != x || y != y || IsInf(x) || y == 0:
+		return math.NaN()
+	case IsInf(y):
+		return x
+	}
+	sign := math.Float64bits(x) & signbit
+	x, y = math.Abs(x), math.Abs(y)
+	if y <= math.MaxFloat64/2 {
+		x = modBits(x, y+y)                    // x < 2y
+	}
+	if y < 0x1p-1021 {
+		if x+x > y {
+			x -= y
+			if x+x >= y {
+				x -= y
+			}
+		}
+	} else {
+		half := 0.5 * y
+		if x > half {
+			x -= y
+			if x >= half {
+				x -= y
+			}
+		}
+	}
+	return math.Float64frombits(math.Float64bits(x) ^ sign)
+}
+
+// Mod returns the floating-point remainder of x/y, x - trunc(x/y)*y as
+// C fmod. It is math.Mod with a faster reduction.
+//
+// The result is exact, has the sign of x and abs(Mod(x, y)) < abs(y).
+// The reduction is modBits, which takes 64 bits of the exponent difference
+// per step, where math.Mod takes one.
+// Special cases are as for math.Mod:
+// Mod(+/-Inf, y)  = NaN
+// Mod(NaN, y)     = NaN
+// Mod(x, 0)       = NaN
+// Mod(x, +/-Inf)  = x
+// Mod(x, NaN)     = NaN
+//
+func Mod(x, y float64) float64 {
+	switch {
+	case x != x || y != y || IsInf(x) || y == 0:
+		return math.NaN()
+	case IsInf(y):
+		return x
+	}
+	sign := math.Float64bits(x) & signbit
+	r := modBits(math.Abs(x), math.Abs(y))
+	return math.Float64frombits(math.Float64bits(r) | sign)
+}
+
+// modBits returns x mod y for finite x >= 0 and y > 0, the exact
+// x - trunc(x/y)*y.
+//
+// x = mx * 2^(ex-1075) and y = my * 2^(ey-1075), where mx and my are the
+// significands with the implicit bit and ex, ey the exponent fields, 1 for
+// subnormals. The result is (mx * 2^(ex-ey)) mod my, times 2^(ey-1075).
+// mx mod my < my < 2^53, so mx * 2^64 mod my is one 128/64 bit division.
+func modBits(x, y float64) float64 {
+	ux, uy := math.Float64bits(x), math.Float64bits(y)
+	if ux < uy {
+		return x
+	}
+	mx, ex := ux&(1<<52-1), int(ux>>52)
+	my, ey := uy&(1<<52-1), int(uy>>52)
+	if ex == 0 {
+		ex = 1
+	} else {
+		mx |= 1 << 52
+	}
+	if ey == 0 {
+		ey = 1
+	} else {
+		my |= 1 << 52
+	}
+	r := mx % my
+	for d := ex - ey; d > 0; {
+		s := d
+		if s > 64 {
+			s = 64
+		}
+		// r*2^s mod my as a 128/64 bit division. r < my after each step, so
+		// the high word r>>(64-s) < my and bits.Div64 doesn't overflow.
+		_, r = bits.Div64(r>>(64-uint(s)), r<<uint(s), my)
+		d -= s
+	}
+	if r == 0 {
+		return 0
+	}
+	sh := 53 - bits.Len64(r)                   // r*2^(ey-1075) = (r<<sh) * 2^(ey-sh-1075)
+	if ey-sh >= 1 {
+		return math.Float64frombits(uint64(ey-sh-1)<<52 + r<<uint(sh))
+	}
+	return math.Float64frombits(r << uint(ey-1))  // subnormal
+}
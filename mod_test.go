@@ -0,0 +1,97 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+func BenchmarkRemainder(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = Remainder(1e300+float64(n), 3.3)
+	}
+	fsink = y
+}
+
+func BenchmarkMathRemainder(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = math.Remainder(1e300+float64(n), 3.3)
+	}
+	fsink = y
+}
+
+func BenchmarkMod(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = Mod(1e300+float64(n), 3.3)
+	}
+	fsink = y
+}
+
+func BenchmarkMathMod(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = math.Mod(1e300+float64(n), 3.3)
+	}
+	fsink = y
+}
+
+// ------------------------------------------------------------- Tests
+
+// randomModPair returns x and y with a random exponent difference, also
+// subnormals and special values.
+func randomModPair(state *uint64) (x, y float64) {
+	x = RandomFloat64(state)
+	y = RandomFloat64(state)
+	switch r := Splitmix(state); r % 8 {
+	case 0:
+		y = math.Ldexp(y, Log2(x)-Log2(y)+int(r>>8%200)-100)
+	case 1:
+		y = math.Float64frombits(Splitmix(state) >> 12)     // subnormal
+	case 2:
+		x = math.Float64frombits(Splitmix(state) >> 12)
+	case 3:
+		x = math.Ldexp(x, Log2(y)-Log2(x)+int(r>>8%4))
+		x = math.Trunc(x/y) * y                            // often exact multiples
+	case 4:
+		s := []float64{0, math.Copysign(0, -1), math.Inf(1), math.Inf(-1), math.NaN(),
+			math.MaxFloat64, 0x1p-1074, 0x1p-1022}
+		x, y = s[r>>8%8], s[r>>16%8]
+	}
+	return
+}
+
+func TestRemainder(t *testing.T) {
+	const rounds int = 1e5
+	t.Logf("Remainder(5, 2)      %v", Remainder(5, 2))
+	t.Logf("Remainder(7, 2)      %v", Remainder(7, 2))
+	t.Logf("Remainder(1e300, 3)  %v", Remainder(1e300, 3))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x, y := randomModPair(&state)
+		r, w := Remainder(x, y), math.Remainder(x, y)
+		if math.Float64bits(r) != math.Float64bits(w) && !(r != r && w != w) {
+			t.Logf("x    %v", x)
+			t.Logf("y    %v", y)
+			t.Fatalf("%v, math.Remainder %v", r, w)
+		}
+	}
+}
+
+func TestMod(t *testing.T) {
+	const rounds int = 1e5
+	t.Logf("Mod(-7, 2)      %v", Mod(-7, 2))
+	t.Logf("Mod(-6, 2)      %v", Mod(-6, 2))
+	t.Logf("Mod(1e300, 3)   %v", Mod(1e300, 3))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x, y := randomModPair(&state)
+		r, w := Mod(x, y), math.Mod(x, y)
+		if math.Float64bits(r) != math.Float64bits(w) && !(r != r && w != w) {
+			t.Logf("x    %v", x)
+			t.Logf("y    %v", y)
+			t.Fatalf("%v, math.Mod %v", r, w)
+		}
+	}
+}
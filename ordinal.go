@@ -0,0 +1,58 @@
+package fbits
+
+import (
+	"math"
+	"math/bits"
+)
+
+// ordinal maps x to an int64 in the order of the float64 values:
+// -NaN < -Inf < -MaxFloat64 < ... < -2^-1074 < +/-0 < 2^-1074 < ... < +Inf < +NaN.
+//
+// Adjacent floats have adjacent ordinals and both zeros map to 0, so
+// ordinal(y) - ordinal(x) is the signed UlpsBetween(x, y) for non-NaNs.
+// The ordinals of -Inf and +Inf are -0x7ff0000000000000 and 0x7ff0000000000000.
+//
+func ordinal(x float64) int64 {
+	u := math.Float64bits(x)
+	if u >= signbit {
+		return -int64(u &^ signbit)
+	}
+	return int64(u)
+}
+
+// fromOrdinal is the inverse of ordinal. Ordinal 0 maps to +0.
+func fromOrdinal(k int64) float64 {
+	if k < 0 {
+		return math.Float64frombits(uint64(-k) | signbit)
+	}
+	return math.Float64frombits(uint64(k))
+}
+
+// totalLess returns true if x is before y in the IEEE 754 total order,
+// which is the ordinal order with -0 before +0.
+func totalLess(x, y float64) bool {
+	k, n := ordinal(x), ordinal(y)
+	if k == n {
+		return math.Signbit(x) && !math.Signbit(y)
+	}
+	return k < n
+}
+
+// ordinalGrid returns n >= 1 floats from lo to hi, lo before hi in total
+// order, equally spaced in ordinals. Neighbours differ by floor or ceil of
+// UlpsBetween(lo, hi) / (n-1) ulps. lo is first and for n > 1 hi is last.
+func ordinalGrid(lo, hi float64, n int) []float64 {
+	k := ordinal(lo)
+	span := uint64(ordinal(hi) - k)              // < 2^64, wraps correctly
+	g := make([]float64, n)
+	g[0] = lo
+	for i := 1; i < n; i++ {
+		h, l := bits.Mul64(span, uint64(i))
+		q, _ := bits.Div64(h, l, uint64(n-1))    // span * i/(n-1)
+		g[i] = fromOrdinal(k + int64(q))
+	}
+	if n > 1 {
+		g[n-1] = hi                              // keeps the sign of a zero
+	}
+	return g
+}
@@ -0,0 +1,225 @@
+package fbits
+
+import "math"
+
+// Snapshot is a saved position of a Splitmix stream. It holds the single
+// uint64 word of the Splitmix state only. The package has no generator
+// with a multi-word state, such as Xoshiro, and Snapshot is not a common
+// interface for one.
+type Snapshot struct {
+	state uint64
+}
+
+// Checkpoint saves the Splitmix state. Continuing from Restore() gives
+// the same numbers as continuing from state.
+func Checkpoint(state uint64) Snapshot {
+	return Snapshot{state}
+}
+
+// Restore returns the saved Splitmix state.
+func (s Snapshot) Restore() uint64 {
+	return s.state
+}
+
+// MixSeed combines the seed words into one Splitmix state.
+//
+// Each word is added to the running value with the Splitmix increment and the
+// sum is fed through the SplitMix64 output function. The order of the words
+// matters, MixSeed(a, b) != MixSeed(b, a) in general, and so does the count,
+// MixSeed(a) != MixSeed(a, 0). MixSeed() returns 0.
+//
+//	state := MixSeed(nameHash, uint64(i))
+//
+func MixSeed(seeds ...uint64) uint64 {
+	var h uint64
+	for _, s := range seeds {
+		h = mix64(h + s + 0x9e3779b97f4a7c15)
+	}
+	return h
+}
+
+// mix64 is the SplitMix64 output function.
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// UlpRandomWalk moves start steps times one ulp up or down with equal
+// probability and returns the final value.
+//
+// The direction is the top bit of Splitmix(state) and a step is
+// NextToward(x, +/-Inf), so the walk crosses zero as
+// 2^-1074, 0, -2^-1074. A walk models the accumulation of
+// independent rounding errors, after n steps it is typically about
+// sqrt(n) ulps from start and never more than n.
+// The walk saturates at +/-Inf: a step outwards from +/-Inf stays at
+// +/-Inf and a step inwards goes to +/-MaxFloat64.
+// Special cases:
+// UlpRandomWalk(state, x, steps <= 0) = x
+// UlpRandomWalk(state, NaN, steps)    = NaN
+//
+func UlpRandomWalk(state *uint64, start float64, steps int) float64 {
+	x := start
+	for i := 0; i < steps; i++ {
+		x = ulpStep(state, x)
+	}
+	return x
+}
+
+// UlpRandomWalkPath is as UlpRandomWalk, but it returns the whole path,
+// start and the steps values after each step. The last element is the
+// value UlpRandomWalk returns for the same state.
+func UlpRandomWalkPath(state *uint64, start float64, steps int) []float64 {
+	if steps < 0 {
+		steps = 0
+	}
+	p := make([]float64, steps+1)
+	p[0] = start
+	for i := 1; i <= steps; i++ {
+		p[i] = ulpStep(state, p[i-1])
+	}
+	return p
+}
+
+func ulpStep(state *uint64, x float64) float64 {
+	if Splitmix(state)>>63 == 0 {
+		return NextToward(x, math.Inf(-1))
+	}
+	return NextToward(x, math.Inf(1))
+}
+
+// RandomCauchy returns a standard Cauchy distributed random float64,
+// tan(pi*(u - 0.5)) for a uniform u in (0, 1).
+//
+// u is one of the 2^52 midpoints (k + 0.5) * 2^-52, all exact in float64,
+// so it is never 0, 0.5 or 1. For u < 0.25 and u > 0.75 the value is
+// computed as -1/tan(pi*u) and -1/tan(pi*(u-1)), which keeps the tails
+// accurate. The result is always finite, the largest magnitudes are about
+// 2^53/pi ~ 2.9e15.
+// The median of the distribution is 0, the quartiles are -1 and 1.
+// The mean and the variance are undefined, the sample mean doesn't converge.
+//
+func RandomCauchy(state *uint64) float64 {
+	u := (float64(Splitmix(state)>>12) + 0.5) * 0x1p-52
+	switch {
+	case u < 0.25:
+		return -1 / math.Tan(math.Pi*u)
+	case u > 0.75:
+		return -1 / math.Tan(math.Pi*(u-1))
+	}
+	return math.Tan(math.Pi * (u - 0.5))
+}
+
+// RandomSmallBiasedFloat64 returns a random finite float64 whose exponent
+// field is geometrically distributed towards zero, so subnormals and
+// tiny normals are frequent.
+//
+// The exponent field k (0 for subnormals, 1 for [2^-1022, 2^-1021), ...)
+// has P(k) = (1-q) q^k with q = e^(-1/32), mean ~31.5. About 3.1% of the
+// floats are subnormal, half are below 2^-1000 and 99.9% below 2^-800.
+// k is capped at 2046. The sign and the 52 significand bits are uniform,
+// so a subnormal can also be a zero.
+//
+func RandomSmallBiasedFloat64(state *uint64) float64 {
+	u := float64(Splitmix(state)>>11+1) * 0x1p-53           // (0, 1]
+	k := uint64(-32 * math.Log(u))
+	if k > 2046 {
+		k = 2046
+	}
+	r := Splitmix(state)
+	return math.Float64frombits(r&signbit | k<<52 | r&(1<<52-1))
+}
+
+// FillUint64 fills dst with Splitmix(state) outputs. The state is advanced
+// len(dst) times, as by len(dst) calls of Splitmix.
+func FillUint64(state *uint64, dst []uint64) {
+	s := *state
+	for i := range dst {
+		s += 0x9e3779b97f4a7c15
+		dst[i] = mix64(s)
+	}
+	*state = s
+}
+
+// RandomRepresentableUnit returns a random float64 from [0, 1), where each
+// of the 0x3ff0000000000000 floats in [0, 1) has the same probability.
+//
+// This is uniform over the representable values, not over the reals:
+// each normal binade [2^(-k-1), 2^-k) has the probability 2^52/0x3ff0000000000000,
+// so the median is about 2^-512 and subnormals have probability ~1/1023.
+// A uniform by value, as float64(Splitmix(state)>>11) * 2^-53, has
+// the median 0.5 and practically never gives floats below 2^-60.
+// The float is the bits u for u < 0x3ff0000000000000, taking Splitmix>>2 and
+// resampling in 1/1024, about 0.1%, of the cases, so the distribution is
+// unbiased.
+//
+func RandomRepresentableUnit(state *uint64) float64 {
+	again:
+	u := Splitmix(state) >> 2
+	if u >= 0x3ff0000000000000 {
+		goto again
+	}
+	return math.Float64frombits(u)
+}
+
+// ExponentSampler keeps a reservoir of random samples per Log2 bucket of a
+// stream of floats, a picture of the dynamic range of the stream without
+// storing it. The zero value is ready to use and keeps 4 samples per bucket.
+//
+// Each bucket is a uniform random sample (reservoir sampling, Algorithm R)
+// of PerBucket values of the stream in the bucket. The random numbers are
+// drawn from State with Splitmix. The bucket of x is Log2(x), so x and -x
+// share a bucket, zeros are in bucket -1075 and +/-Inf in bucket 1024.
+// NaNs are not sampled, they are only counted by NaNs.
+//
+type ExponentSampler struct {
+	PerBucket int                // samples per bucket, 4 if <= 0
+	State     uint64             // Splitmix state
+	seen      map[int]int
+	samples   map[int][]float64
+	nans      int
+}
+
+// Add adds x to the stream.
+func (s *ExponentSampler) Add(x float64) {
+	if x != x {
+		s.nans++
+		return
+	}
+	if s.seen == nil {
+		s.seen = make(map[int]int)
+		s.samples = make(map[int][]float64)
+	}
+	capacity := s.PerBucket
+	if capacity <= 0 {
+		capacity = 4
+	}
+	b := Log2(x)
+	s.seen[b]++
+	if r := s.samples[b]; len(r) < capacity {
+		s.samples[b] = append(r, x)
+	} else if j := Splitmix(&s.State) % uint64(s.seen[b]); j < uint64(capacity) {
+		r[j] = x
+	}
+}
+
+// Samples returns a copy of the samples by bucket. Only buckets with
+// values are present.
+func (s *ExponentSampler) Samples() map[int][]float64 {
+	m := make(map[int][]float64, len(s.samples))
+	for b, r := range s.samples {
+		m[b] = append([]float64(nil), r...)
+	}
+	return m
+}
+
+// Seen returns the number of values of the stream in bucket b.
+func (s *ExponentSampler) Seen(b int) int {
+	return s.seen[b]
+}
+
+// NaNs returns the number of NaNs in the stream.
+func (s *ExponentSampler) NaNs() int {
+	return s.nans
+}
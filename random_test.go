@@ -0,0 +1,290 @@
+package fbits
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func BenchmarkRandomCauchy(b *testing.B) {
+	var y float64
+	state := uint64(1)
+	for n := 0; n < b.N; n++ {
+		y = RandomCauchy(&state)
+	}
+	fsink = y
+}
+
+func BenchmarkRandomSmallBiasedFloat64(b *testing.B) {
+	var y float64
+	state := uint64(1)
+	for n := 0; n < b.N; n++ {
+		y = RandomSmallBiasedFloat64(&state)
+	}
+	fsink = y
+}
+
+func BenchmarkFillUint64(b *testing.B) {
+	dst := make([]uint64, 1024)
+	state := uint64(1)
+	b.SetBytes(8 * 1024)
+	for n := 0; n < b.N; n++ {
+		FillUint64(&state, dst)
+	}
+	usink = dst[0]
+}
+
+func BenchmarkRandomRepresentableUnit(b *testing.B) {
+	var y float64
+	state := uint64(1)
+	for n := 0; n < b.N; n++ {
+		y = RandomRepresentableUnit(&state)
+	}
+	fsink = y
+}
+
+func BenchmarkExponentSampler(b *testing.B) {
+	var s ExponentSampler
+	state := uint64(1)
+	for n := 0; n < b.N; n++ {
+		s.Add(RandomFloat64(&state))
+	}
+	isink = s.Seen(0)
+}
+
+// ------------------------------------------------------------- Tests
+func TestCheckpoint(t *testing.T) {
+	state := uint64(1)
+	for i := 0; i < 100; i++ {
+		Splitmix(&state)
+	}
+	saved := Checkpoint(state)
+	want := make([]uint64, 100)
+	for i := range want {
+		want[i] = Splitmix(&state)
+	}
+	state = saved.Restore()
+	for i := range want {
+		if u := Splitmix(&state); u != want[i] {
+			t.Fatalf("i %d: %X, want %X", i, u, want[i])
+		}
+	}
+}
+
+func TestMixSeed(t *testing.T) {
+	a, b := uint64(12345), uint64(7)
+	t.Logf("MixSeed(a, b)  %X", MixSeed(a, b))
+	t.Logf("MixSeed(b, a)  %X", MixSeed(b, a))
+	if MixSeed(a, b) != MixSeed(a, b) {
+		t.Fatalf("not deterministic")
+	}
+	if MixSeed(a, b) == MixSeed(b, a) || MixSeed(a) == MixSeed(a, 0) || MixSeed(0) == MixSeed() {
+		t.Fatalf("collision")
+	}
+	// One word is one Splitmix step from that word.
+	state := a
+	if MixSeed(a) != Splitmix(&state) {
+		t.Fatalf("MixSeed(a) != Splitmix")
+	}
+	seen := make(map[uint64]bool)
+	for i := uint64(0); i < 1000; i++ {
+		for j := uint64(0); j < 100; j++ {
+			s := MixSeed(i, j)
+			if seen[s] {
+				t.Fatalf("duplicate seed for %d, %d", i, j)
+			}
+			seen[s] = true
+		}
+	}
+}
+
+func TestUlpRandomWalk(t *testing.T) {
+	const rounds int = 1e4
+	const steps = 200
+	inf := math.Inf(1)
+	starts := []float64{1, -1, 0, 0x1p-1074, 0x1p-1022, 0x1p53, math.MaxFloat64, inf, -inf}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		start := starts[i%len(starts)]
+		if i%len(starts) == 0 {
+			start = RandomFloat64(&state)
+		}
+		saved := state
+		x := UlpRandomWalk(&state, start, steps)
+		state = saved
+		p := UlpRandomWalkPath(&state, start, steps)
+		if len(p) != steps+1 || p[0] != start || p[steps] != x {
+			t.Fatalf("start %v: path %d values, last %v, walk %v", start, len(p), p[steps], x)
+		}
+		if d := UlpsBetween(start, x); d > steps {
+			t.Logf("start  %v", start)
+			t.Fatalf("x      %v  %d ulps", x, d)
+		}
+		for j := 1; j < len(p); j++ {
+			if d := UlpsBetween(p[j-1], p[j]); d > 1 {
+				t.Fatalf("start %v: step %d is %d ulps", start, j, d)
+			}
+		}
+	}
+	t.Logf("1 + walk    %v", UlpRandomWalk(&state, 1, steps))
+	if !IsNaN(UlpRandomWalk(&state, math.NaN(), 10)) || UlpRandomWalk(&state, 2, 0) != 2 {
+		t.Fatalf("special cases")
+	}
+}
+
+func TestRandomCauchy(t *testing.T) {
+	const rounds int = 1e6
+	s := make([]float64, rounds)
+	state := uint64(1)
+	for i := range s {
+		s[i] = RandomCauchy(&state)
+		if !IsFinite(s[i]) {
+			t.Fatalf("i %d: %v", i, s[i])
+		}
+	}
+	sort.Float64s(s)
+	median := s[rounds/2]
+	q1, q3 := s[rounds/4], s[3*rounds/4]
+	t.Logf("median %v, quartiles %v %v", median, q1, q3)
+	t.Logf("min %v, max %v", s[0], s[rounds-1])
+	// The standard deviation of the sample median is pi/(2*sqrt(rounds)) ~ 0.0016.
+	if math.Abs(median) > 0.01 || math.Abs(q1+1) > 0.02 || math.Abs(q3-1) > 0.02 {
+		t.Fatalf("median %v, quartiles %v %v", median, q1, q3)
+	}
+	// Splitmix mixes 0xcf9a04affa6badc0 and the top 53 bits of the output
+	// are all ones. With 53 bits k + 0.5 rounded to 2^53 and u to 1.
+	state = 0x31628af67b2131ab
+	if x := RandomCauchy(&state); !IsFinite(x) {
+		t.Fatalf("state 0x31628af67b2131ab: %v", x)
+	}
+}
+
+func TestRandomSmallBiasedFloat64(t *testing.T) {
+	const rounds int = 1e7
+	var c ClassCounts
+	below1000, below800, negatives := 0, 0, 0
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomSmallBiasedFloat64(&state)
+		c[Classify(x)]++
+		if math.Abs(x) < 0x1p-1000 {
+			below1000++
+		}
+		if math.Abs(x) < 0x1p-800 {
+			below800++
+		}
+		if math.Signbit(x) {
+			negatives++
+		}
+	}
+	t.Logf("%v", c)
+	frac := func(n int) float64 { return float64(n) / float64(rounds) }
+	t.Logf("below 2^-1000 %v, below 2^-800 %v, negative %v", frac(below1000), frac(below800), frac(negatives))
+	if c[Infinity] != 0 || c[NaN] != 0 {
+		t.Fatalf("not finite: %v", c)
+	}
+	if math.Abs(frac(c[Subnormal]+c[Zero])-(1-math.Exp(-1.0/32))) > 0.001 ||
+		math.Abs(frac(below1000)-(1-math.Exp(-23.0/32))) > 0.002 || frac(below800) < 0.998 ||
+		math.Abs(frac(negatives)-0.5) > 0.001 {
+		t.Fatalf("distribution")
+	}
+}
+
+func TestFillUint64(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 1000} {
+		dst := make([]uint64, n)
+		state, s := uint64(12345), uint64(12345)
+		FillUint64(&state, dst)
+		for i := range dst {
+			if u := Splitmix(&s); dst[i] != u {
+				t.Fatalf("n %d, i %d: %X, Splitmix %X", n, i, dst[i], u)
+			}
+		}
+		if state != s {
+			t.Fatalf("n %d: state %X, want %X", n, state, s)
+		}
+	}
+}
+
+func TestRandomRepresentableUnit(t *testing.T) {
+	const rounds int = 1e6
+	s := make([]float64, rounds)
+	subnormals := 0
+	state := uint64(1)
+	for i := range s {
+		x := RandomRepresentableUnit(&state)
+		if !(0 <= x && x < 1) || math.Signbit(x) {
+			t.Fatalf("i %d: %v", i, x)
+		}
+		if x < 0x1p-1022 {
+			subnormals++
+		}
+		s[i] = x
+	}
+	sort.Float64s(s)
+	median := s[rounds/2]
+	t.Logf("median %v (2^%d), subnormals %d, max %v", median, Log2(median), subnormals, s[rounds-1])
+	if Log2(median) < -515 || Log2(median) > -509 {
+		t.Fatalf("median %v", median)
+	}
+	if p := float64(subnormals) / float64(rounds); math.Abs(p-1.0/1023) > 0.0003 {
+		t.Fatalf("subnormal fraction %v", p)
+	}
+}
+
+func TestExponentSampler(t *testing.T) {
+	var s ExponentSampler
+	for i := 0; i < 1000; i++ {
+		s.Add(1 + float64(i)/1000)                  // bucket 0
+	}
+	for i := 0; i < 3; i++ {
+		s.Add(-0x1p10 * (1 + float64(i)/4))         // bucket 10
+	}
+	s.Add(0)
+	s.Add(math.Copysign(0, -1))
+	s.Add(0x1p-1074)
+	s.Add(math.Inf(-1))
+	s.Add(math.NaN())
+	m := s.Samples()
+	want := map[int]int{0: 4, 10: 3, -1075: 2, -1074: 1, 1024: 1}
+	if len(m) != len(want) || s.NaNs() != 1 {
+		t.Fatalf("buckets %v  NaNs %d", m, s.NaNs())
+	}
+	for b, n := range want {
+		t.Logf("bucket %5d  seen %4d  %v", b, s.Seen(b), m[b])
+		if len(m[b]) != n {
+			t.Fatalf("bucket %d: %d samples, want %d", b, len(m[b]), n)
+		}
+		for _, x := range m[b] {
+			if Log2(x) != b {
+				t.Fatalf("bucket %d: %v", b, x)
+			}
+		}
+	}
+	if s.Seen(0) != 1000 || s.Seen(10) != 3 {
+		t.Fatalf("seen %d %d", s.Seen(0), s.Seen(10))
+	}
+	m[0][0] = 100
+	if s.Samples()[0][0] == 100 {
+		t.Fatalf("Samples is not a copy")
+	}
+	// Each of 10 values is kept with probability PerBucket/10.
+	const rounds int = 1e4
+	var hits [10]int
+	for i := 0; i < rounds; i++ {
+		s := ExponentSampler{PerBucket: 3, State: uint64(i)}
+		for k := 0; k < 10; k++ {
+			s.Add(1 + float64(k)/16)
+		}
+		for _, x := range s.Samples()[0] {
+			hits[int((x-1)*16)]++
+		}
+	}
+	for k, h := range hits {
+		p := float64(h) / float64(rounds)
+		if math.Abs(p-0.3) > 0.02 {
+			t.Fatalf("value %d kept with probability %.3f, want 0.3", k, p)
+		}
+	}
+	t.Logf("hits %v", hits)
+}
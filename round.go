@@ -0,0 +1,58 @@
+package fbits
+
+import (
+	"math"
+)
+
+// QuantizeToStep rounds x to the nearest integer multiple of step, ties to
+// the even multiple.
+//
+// With r = math.Remainder(x, step), x - r is exactly n*step, where n is the
+// exactly rounded x/step. r is exact, so x - r rounds only once and
+// the result is the correctly rounded n*step. The usual Round(x/step) * step
+// rounds up to three times and can be off for large x/step.
+// Special cases:
+// QuantizeToStep(x, step <= 0)    = NaN
+// QuantizeToStep(x, NaN)          = NaN
+// QuantizeToStep(NaN, step)       = NaN
+// QuantizeToStep(+/-Inf, step)    = +/-Inf
+// QuantizeToStep(x, +Inf)         = +/-0    x finite, 0*Inf is the nearest multiple
+//
+func QuantizeToStep(x, step float64) float64 {
+	switch {
+	case !(step > 0):
+		return math.NaN()
+	case IsInf(x):
+		return x
+	case IsInf(step):
+		return x * 0
+	}
+	return x - math.Remainder(x, step)
+}
+
+// TieBreakers returns positive floats in [2^exp, 2^(exp+1)) which are
+// exactly halfway between two neighbours at a lower precision, for
+// testing round to nearest, ties to even.
+//
+// For k = 1, ..., 51 dropped low bits, the significand is 1.xxx with the
+// dropped part 100...0 (binary) and the lowest kept bit 0 or 1:
+// result[2k-2] has an even kept part, which ties to even rounds down,
+// result[2k-1] an odd kept part, which rounds up. The last float is
+// 1.5 * 2^exp, a tie with k = 52 dropped bits, rounding 1.1 (binary) to 2.
+// Rounding to float32 drops k = 29 bits, result[56] and result[57].
+// For exp outside the normal range -1022 ... 1023 TieBreakers returns nil.
+//
+func TieBreakers(exp int) []float64 {
+	if exp < -1022 || exp > 1023 {
+		return nil
+	}
+	e := uint64(exp+1023) << 52
+	s := make([]float64, 0, 103)
+	for k := uint(1); k <= 51; k++ {
+		half := uint64(1) << (k - 1)
+		s = append(s,
+			math.Float64frombits(e|half),           // kept part even
+			math.Float64frombits(e|1<<k|half))      // kept part odd
+	}
+	return append(s, math.Float64frombits(e|1<<51))
+}
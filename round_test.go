@@ -0,0 +1,114 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func BenchmarkQuantizeToStep(b *testing.B) {
+	var y float64
+	for n := 0; n < b.N; n++ {
+		y = QuantizeToStep(float64(n), 0.3)
+	}
+	fsink = y
+}
+
+// quantizeBig is QuantizeToStep with big.Rat arithmetic.
+func quantizeBig(x, step float64) float64 {
+	q := new(big.Rat).Quo(new(big.Rat).SetFloat64(x), new(big.Rat).SetFloat64(step))
+	n, m := new(big.Int).DivMod(q.Num(), q.Denom(), new(big.Int))   // floor(q), q.Denom() > 0
+	switch new(big.Int).Lsh(m, 1).Cmp(q.Denom()) {
+	case 1:
+		n.Add(n, big.NewInt(1))
+	case 0:
+		if n.Bit(0) == 1 {
+			n.Add(n, big.NewInt(1))
+		}
+	}
+	r := new(big.Rat).SetInt(n)
+	f, _ := r.Mul(r, new(big.Rat).SetFloat64(step)).Float64()
+	return f
+}
+
+// ------------------------------------------------------------- Tests
+func TestQuantizeToStep(t *testing.T) {
+	const rounds int = 1e5
+	cases := [][3]float64{
+		{2.5, 1, 2}, {3.5, 1, 4}, {-2.5, 1, -2}, {0.25, 0.5, 0}, {0.75, 0.5, 1},
+		{7, 2, 8}, {5, 2, 4}, {1.3, 0.25, 1.25}, {0x1p60 + 0x1p9, 3, 0x1p60 + 0x1p9},
+		{1, math.Inf(1), 0}, {math.Inf(-1), 1, math.Inf(-1)},
+	}
+	for _, c := range cases {
+		q := QuantizeToStep(c[0], c[1])
+		t.Logf("%-24v %-6v %v", c[0], c[1], q)
+		if q != c[2] {
+			t.Fatalf("QuantizeToStep(%v, %v) = %v, want %v", c[0], c[1], q, c[2])
+		}
+	}
+	for _, s := range []float64{0, -1, math.NaN()} {
+		if q := QuantizeToStep(1, s); q == q {
+			t.Fatalf("QuantizeToStep(1, %v) = %v", s, q)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		step := math.Abs(math.Ldexp(RandomFloat64(&state), Log2(x)-int(Splitmix(&state)%60)))
+		if i&7 == 0 {
+			x = QuantizeToStep(x, step) + step/2                   // ties
+		}
+		if step == 0 || !IsFinite(step) || !IsFinite(x) {
+			continue
+		}
+		q, want := QuantizeToStep(x, step), quantizeBig(x, step)
+		if q != want {
+			t.Logf("i     %d", i)
+			t.Logf("x     %v", x)
+			t.Logf("step  %v", step)
+			t.Fatalf("q     %v, want %v", q, want)
+		}
+	}
+}
+
+func TestTieBreakers(t *testing.T) {
+	for _, exp := range []int{-1022, -1, 0, 52, 1023} {
+		s := TieBreakers(exp)
+		if len(s) != 103 {
+			t.Fatalf("exp %d: %d values", exp, len(s))
+		}
+		for i, x := range s {
+			k := uint(i/2 + 1)
+			if Log2(x) != exp {
+				t.Fatalf("exp %d: %v not in the binade", exp, x)
+			}
+			prec := 53 - k
+			round := func(mode big.RoundingMode) *big.Float {
+				return new(big.Float).SetPrec(prec).SetMode(mode).SetFloat64(x)
+			}
+			down, up, even := round(big.ToZero), round(big.AwayFromZero), round(big.ToNearestEven)
+			bx := new(big.Float).SetFloat64(x)
+			d1 := new(big.Float).Sub(bx, down)
+			d2 := new(big.Float).Sub(up, bx)
+			if down.Cmp(up) == 0 || d1.Cmp(d2) != 0 {
+				t.Fatalf("exp %d, i %d: %v is not a tie at %d bits", exp, i, x, prec)
+			}
+			want := down
+			if i%2 == 1 || i == 102 {
+				want = up
+			}
+			if even.Cmp(want) != 0 {
+				t.Fatalf("exp %d, i %d: %v rounds to %v at %d bits", exp, i, x, even, prec)
+			}
+		}
+	}
+	s := TieBreakers(0)
+	t.Logf("%v %v %v", s[0], s[1], s[102])
+	t.Logf("float32  %v -> %v   %v -> %v", s[56], float32(s[56]), s[57], float32(s[57]))
+	if float64(float32(s[56])) != 1 || float64(float32(s[57])) != 1+0x1p-22 {
+		t.Fatalf("float32 ties")
+	}
+	if TieBreakers(-1023) != nil || TieBreakers(1024) != nil {
+		t.Fatalf("exp out of range")
+	}
+}
@@ -0,0 +1,483 @@
+package fbits
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// Product returns the product of the elements of s.
+//
+// The significands and the exponents are multiplied and added separately,
+// so an intermediate product doesn't overflow to Inf or underflow to zero.
+// The result is +/-Inf or +/-0 only if the true product overflows or underflows.
+// Each multiplication rounds once as in a naive loop, and a subnormal
+// result is rounded a second time.
+// Special cases:
+// Product(nil)          = 1
+// Product(s with NaN)   = NaN
+// Product(s with Inf)   = +/-Inf, or NaN if s also has a zero
+//
+func Product(s []float64) float64 {
+	m, e := 1.0, 0
+	for _, x := range s {
+		f, k := math.Frexp(x)          // x = f * 2^k, 0.5 <= abs(f) < 1
+		m *= f
+		e += k
+		if -0x1p-500 < m && m < 0x1p-500 {     // renormalize well before m can underflow
+			f, k = math.Frexp(m)
+			m = f
+			e += k
+		}
+	}
+	return math.Ldexp(m, e)
+}
+
+// SortAndCluster sorts s in place in total order and splits it into clusters
+// of consecutive elements which are at most maxUlps apart.
+//
+// The clusters are subslices of s. Two neighbours are in the same cluster if
+// UlpsBetween(s[i-1], s[i]) <= maxUlps, so a cluster can be wider than
+// maxUlps. In the total order -0 is before +0 and negative NaNs are first and
+// positive NaNs last. UlpsBetween(x, NaN) is maxUint64, so each NaN is a
+// cluster of its own, unless maxUlps is maxUint64.
+// SortAndCluster of an empty s returns nil.
+//
+func SortAndCluster(s []float64, maxUlps uint64) [][]float64 {
+	if len(s) == 0 {
+		return nil
+	}
+	sort.Slice(s, func(i, j int) bool { return totalLess(s[i], s[j]) })
+	var c [][]float64
+	first := 0
+	for i := 1; i < len(s); i++ {
+		if UlpsBetween(s[i-1], s[i]) > maxUlps {
+			c = append(c, s[first:i])
+			first = i
+		}
+	}
+	return append(c, s[first:])
+}
+
+// AlmostEqualSlice returns true, -1 if len(x) == len(y) and
+// UlpsBetween(x[i], y[i]) <= maxUlps for all i.
+//
+// Otherwise it returns false and the first index i where the elements
+// differ by more than maxUlps. If the lengths differ and the common part is
+// close, the index is the length of the shorter slice.
+// A NaN is never close to anything, unless maxUlps is maxUint64.
+//
+func AlmostEqualSlice(x, y []float64, maxUlps uint64) (allClose bool, firstDiffIndex int) {
+	n := len(x)
+	if len(y) < n {
+		n = len(y)
+	}
+	for i := 0; i < n; i++ {
+		if UlpsBetween(x[i], y[i]) > maxUlps {
+			return false, i
+		}
+	}
+	if len(x) != len(y) {
+		return false, n
+	}
+	return true, -1
+}
+
+// CompoundProduct returns the growth factor (1+rates[0]) * (1+rates[1]) * ...
+//
+// The logarithms Log1p(rates[i]) are summed with Neumaier's compensated
+// summation and the factor is Exp(sum) corrected by the compensation term.
+// Log1p is accurate for small rates, where 1 + rates[i] would round away
+// most digits of the rate. The compensation removes the rounding error of
+// the summation. Each Log1p is still rounded, but relative to
+// log1p(rates[i]) ~ rates[i], so the relative error of the result is about
+// sum(abs(rates[i])) * 2^-53 plus the rounding of Exp, where the naive
+// product loop has about count * 2^-53. For small rates the result is
+// within a few ulps.
+// Rates < -1 give negative factors, which are done with Log(abs(1 + rate))
+// and a separate sign.
+// Special cases:
+// CompoundProduct(nil)                = 1
+// CompoundProduct(rates with NaN)     = NaN
+// CompoundProduct(rates with -1)      = +/-0, or NaN with a +/-Inf rate
+// CompoundProduct(rates with +/-Inf)  = +/-Inf
+//
+func CompoundProduct(rates []float64) float64 {
+	sum, c := 0.0, 0.0
+	sign := 1.0
+	for _, r := range rates {
+		var l float64
+		if r < -1 {
+			l = math.Log(-(1 + r))
+			sign = -sign
+		} else {
+			l = math.Log1p(r)
+		}
+		t := sum + l
+		if math.Abs(sum) >= math.Abs(l) {
+			c += (sum - t) + l
+		} else {
+			c += (l - t) + sum
+		}
+		sum = t
+	}
+	if IsInf(sum) || sum != sum {
+		return sign * math.Exp(sum)          // Inf - Inf is NaN here
+	}
+	e := math.Exp(sum)
+	return sign * (e + e*c)
+}
+
+// AdjacentSequence returns start and the n-1 floats following it in
+// increasing order, each one ulp after the previous one.
+//
+// The steps are NextToward(x, +Inf). The sequence crosses zero as
+// -2^-1074, -0, 2^-1074, so +0 is not in it. Adjacent(-0, 2^-1074) is false,
+// use AdjacentFP or UlpsBetween == 1 to check the crossing. The sequence
+// saturates at +Inf, after MaxFloat64 all values are +Inf.
+// Special cases:
+// AdjacentSequence(start, n <= 0) = nil
+// AdjacentSequence(NaN, n)        = n NaNs
+//
+func AdjacentSequence(start float64, n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	s := make([]float64, n)
+	s[0] = start
+	inf := math.Inf(1)
+	for i := 1; i < n; i++ {
+		s[i] = NextToward(s[i-1], inf)
+	}
+	return s
+}
+
+// MeanPairwise returns the mean of the elements of s, summed by
+// pairwise (cascade) summation.
+//
+// The slice is halved recursively down to blocks of 8, which are summed
+// in a loop. The rounding error of the sum grows as O(log n) instead
+// of O(n) for the naive running sum.
+// If the sum overflows to Inf, or to NaN as Inf - Inf of two overflowed
+// halves, and the elements are finite, they are summed again scaled down by
+// a power of two, so the mean is finite if the elements are.
+// Special cases:
+// MeanPairwise(nil)              = NaN
+// MeanPairwise(s with NaN)       = NaN
+// MeanPairwise(s with +Inf, -Inf) = NaN
+//
+func MeanPairwise(s []float64) float64 {
+	n := len(s)
+	if n == 0 {
+		return math.NaN()
+	}
+	sum := pairwiseSum(s, 1)
+	if !IsFinite(sum) && allFinite(s) {
+		k := bits.Len(uint(n))                   // n <= 2^k
+		sum = pairwiseSum(s, math.Ldexp(1, -k))
+		return sum / float64(n) * math.Ldexp(1, k)
+	}
+	return sum / float64(n)
+}
+
+// allFinite returns true if s has no Inf or NaN.
+func allFinite(s []float64) bool {
+	for _, x := range s {
+		if !IsFinite(x) {
+			return false
+		}
+	}
+	return true
+}
+
+// pairwiseSum returns the pairwise sum of scale * s[i].
+func pairwiseSum(s []float64, scale float64) float64 {
+	if len(s) <= 8 {
+		sum := 0.0
+		for _, x := range s {
+			sum += x * scale
+		}
+		return sum
+	}
+	h := len(s) / 2
+	return pairwiseSum(s[:h], scale) + pairwiseSum(s[h:], scale)
+}
+
+// ResampleUniformUlps returns n floats from s[0] to s[len(s)-1], equally
+// spaced in ulps, for a slice s sorted in increasing order.
+//
+// The floats are equally spaced in the ordinals, the positions in the
+// order of all float64's, so each binade between the ends gets about the
+// same number of floats. The step is UlpsBetween(s[0], s[len(s)-1]) / (n-1)
+// ulps, rounded down or up, so neighbours differ by at most 1 ulp in
+// spacing. Only the end points of s are used. If s[0] is after
+// s[len(s)-1], the result is in decreasing order.
+// Special cases:
+// ResampleUniformUlps(s, n <= 0)         = nil
+// ResampleUniformUlps(s, 1)              = [s[0]]
+// ResampleUniformUlps(nil, n)            = nil
+// ResampleUniformUlps(s, n), NaN at ends = nil
+//
+func ResampleUniformUlps(s []float64, n int) []float64 {
+	if n <= 0 || len(s) == 0 {
+		return nil
+	}
+	lo, hi := s[0], s[len(s)-1]
+	if lo != lo || hi != hi {
+		return nil
+	}
+	if totalLess(hi, lo) {
+		g := ordinalGrid(hi, lo, n)
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			g[i], g[j] = g[j], g[i]
+		}
+		return g
+	}
+	return ordinalGrid(lo, hi, n)
+}
+
+// SumOrderIndependent returns true if the sums of s from left to right
+// and from right to left are at most maxUlps apart.
+//
+// A false result shows that the rounding errors of the naive sum depend
+// on the order of s, and a compensated or pairwise sum, see MeanPairwise,
+// may be needed. Agreement doesn't prove that the sums are accurate.
+// An overflow in one order only is not always detected,
+// UlpsBetween(+Inf, MaxFloat64) is 1.
+// Special cases:
+// SumOrderIndependent(nil, n)             = true
+// SumOrderIndependent(s with NaN, n)      = false, unless n is maxUint64
+// SumOrderIndependent(s with +Inf, -Inf)  = false, unless n is maxUint64
+//
+func SumOrderIndependent(s []float64, maxUlps uint64) bool {
+	fwd, bwd := 0.0, 0.0
+	for i := range s {
+		fwd += s[i]
+		bwd += s[len(s)-1-i]
+	}
+	return UlpsBetween(fwd, bwd) <= maxUlps
+}
+
+// UlpHausdorff returns the Hausdorff distance between the sets a and b
+// in ulps, the largest distance from a point of one set to the nearest
+// point of the other set.
+//
+// a and b must be sorted in increasing order. For each point the nearest
+// points of the other set are found by merging, so the time is
+// O(len(a) + len(b)). +0 and -0 are the same point.
+// Special cases:
+// UlpHausdorff(nil, nil)          = 0
+// UlpHausdorff(a, nil)            = maxUint64, a not empty
+// UlpHausdorff(a with NaN, b)     = maxUint64
+//
+func UlpHausdorff(a, b []float64) uint64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	d := directedUlpHausdorff(a, b)
+	if e := directedUlpHausdorff(b, a); e > d {
+		d = e
+	}
+	return d
+}
+
+// directedUlpHausdorff returns the largest distance in ulps from a point
+// of a to the nearest point of b.
+func directedUlpHausdorff(a, b []float64) uint64 {
+	if len(b) == 0 {
+		return maxUint64
+	}
+	var max uint64
+	j := 0
+	for _, x := range a {
+		for j < len(b) && b[j] < x {
+			j++
+		}
+		d := uint64(maxUint64)
+		if j < len(b) {
+			d = UlpsBetween(x, b[j])
+		}
+		if j > 0 {
+			if e := UlpsBetween(x, b[j-1]); e < d {
+				d = e
+			}
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// SuggestUlpThreshold returns a maxUlps for SortAndCluster, which splits s
+// at its most natural gap.
+//
+// The ulp gaps between neighbours of s sorted in total order are sorted,
+// and the threshold is the gap below the largest ratio between two
+// consecutive gaps. Gaps up to the threshold are inside the clusters and
+// larger gaps separate them. If no two gaps differ by a factor of 2 or more,
+// there is no natural gap and the largest gap is returned, one cluster.
+// s is not modified.
+// Special cases:
+// SuggestUlpThreshold(s), len(s) < 2    = 0
+// SuggestUlpThreshold(all equal)        = 0
+// SuggestUlpThreshold(uniform spacing)  = the spacing, one cluster
+// SuggestUlpThreshold(s with NaN)       = NaN gaps are maxUint64 and split
+//
+func SuggestUlpThreshold(s []float64) uint64 {
+	if len(s) < 2 {
+		return 0
+	}
+	t := append([]float64(nil), s...)
+	sort.Slice(t, func(i, j int) bool { return totalLess(t[i], t[j]) })
+	g := make([]uint64, len(t)-1)
+	for i := range g {
+		g[i] = UlpsBetween(t[i], t[i+1])
+	}
+	sort.Slice(g, func(i, j int) bool { return g[i] < g[j] })
+	best, ratio := len(g)-1, 2.0
+	for i := 0; i+1 < len(g); i++ {
+		r := (float64(g[i+1]) + 1) / (float64(g[i]) + 1)    // +1, gaps can be 0
+		if r >= ratio {
+			best, ratio = i, r
+		}
+	}
+	return g[best]
+}
+
+// NearestFloat returns the element of sorted nearest to target in ulps,
+// its index and UlpsBetween(value, target).
+//
+// sorted must be in increasing order, as sorted by SortAndCluster, and the
+// search is binary in ordinals. Of two equally near elements the first is
+// returned. +0 and -0 are the same point.
+// Special cases:
+// NearestFloat(nil, x)                = NaN, -1, maxUint64
+// NearestFloat(s, NaN)                = NaN, -1, maxUint64
+// NearestFloat(s, x), s only NaNs     = NaN, 0, maxUint64
+//
+func NearestFloat(sorted []float64, target float64) (value float64, index int, ulps uint64) {
+	if len(sorted) == 0 || target != target {
+		return math.NaN(), -1, maxUint64
+	}
+	k := ordinal(target)
+	i := sort.Search(len(sorted), func(i int) bool { return ordinal(sorted[i]) >= k })
+	if i == len(sorted) {                       // all before target
+		i--
+	}
+	index, ulps = i, UlpsBetween(sorted[i], target)
+	if i > 0 {
+		if d := UlpsBetween(sorted[i-1], target); d <= ulps {
+			index, ulps = i-1, d
+		}
+	}
+	return sorted[index], index, ulps
+}
+
+// UlpsBetweenStrided returns the largest UlpsBetween(x[i*strideX],
+// y[i*strideY]) for i = 0, ..., n-1 and the first i where it occurs.
+//
+// This compares a field of interleaved data, an array of structs flattened
+// to []float64, without copying: the third field of records of 4 floats is
+// x[2:] with stride 4. A NaN gives maxUint64.
+// The strides and n are validated against the lengths and UlpsBetweenStrided
+// doesn't panic.
+// Special cases:
+// UlpsBetweenStrided(x, y, sx, sy, 0)                   = 0, -1
+// UlpsBetweenStrided, n < 0, stride < 1 or out of range = maxUint64, -1
+//
+func UlpsBetweenStrided(x, y []float64, strideX, strideY, n int) (maxUlps uint64, index int) {
+	switch {
+	case n == 0:
+		return 0, -1
+	case n < 0 || strideX < 1 || strideY < 1 || len(x) < 1 || len(y) < 1 ||
+		(n-1) > (len(x)-1)/strideX || (n-1) > (len(y)-1)/strideY:
+		return maxUint64, -1
+	}
+	for i := 0; i < n; i++ {
+		if d := UlpsBetween(x[i*strideX], y[i*strideY]); d > maxUlps {
+			maxUlps, index = d, i
+		}
+	}
+	return maxUlps, index
+}
+
+// ScaleAndClampSlice sets s[i] to s[i] * scale clamped into [lo, hi], in
+// place in one pass.
+//
+// The loop body has no calls and the clamping is two compares, which
+// the compiler can turn into conditional moves. NaNs propagate: a NaN
+// element, or a product 0 * Inf, stays NaN, as the compares with NaN are
+// false. A NaN bound doesn't clamp. lo > hi gives hi.
+//
+func ScaleAndClampSlice(s []float64, scale float64, lo, hi float64) {
+	for i := range s {
+		y := s[i] * scale
+		if y < lo {
+			y = lo
+		}
+		if y > hi {
+			y = hi
+		}
+		s[i] = y
+	}
+}
+
+// DedupeByUlps collapses each run of sorted which is within maxUlps of the
+// first element of the run into that first element, and returns the
+// reduced slice.
+//
+// sorted is compacted in place, as by slices.Compact, and the result
+// shares its backing array. sorted should be in increasing order, as by
+// SortAndCluster, then the kept values are more than maxUlps apart.
+// Each removed value is at most maxUlps from the kept value before it, a
+// run doesn't drift as the clusters of SortAndCluster can.
+// With maxUlps 0 only equal values are removed, +0 and -0 are equal and the
+// first is kept. NaNs, first and last in total order, are never within
+// maxUlps and are all kept, unless maxUlps is maxUint64.
+//
+func DedupeByUlps(sorted []float64, maxUlps uint64) []float64 {
+	if len(sorted) == 0 {
+		return sorted
+	}
+	k := 0
+	for i := 1; i < len(sorted); i++ {
+		if UlpsBetween(sorted[k], sorted[i]) > maxUlps {
+			k++
+			sorted[k] = sorted[i]
+		}
+	}
+	return sorted[:k+1]
+}
+
+// MinBitsForSlice returns the least significand width, counting the
+// leading 1 bit, which represents every element of s exactly. A float32 has
+// 24 bits and a bfloat16 8, so MinBitsForSlice(s) <= 24 tells that the
+// significands of s survive a conversion to float32.
+//
+// The width of x is the number of bits from the leading to the trailing 1
+// of its significand, 1 for powers of two, 53 at most. The exponent range
+// is not checked, see Float32RoundTripUlps for that.
+// Special cases:
+// MinBitsForSlice(nil)             = 0
+// MinBitsForSlice(only zeros)      = 0
+// MinBitsForSlice(s with Inf/NaN)  = 53, the full float64 width
+//
+func MinBitsForSlice(s []float64) int {
+	max := 0
+	for _, x := range s {
+		u := math.Float64bits(x) &^ signbit
+		switch {
+		case u >= posInf:
+			return 53
+		case u == 0:
+			continue
+		case u >= 1<<52:
+			u = u&(1<<52-1) | 1<<52             // normal, the implicit bit
+		}
+		if n := bits.Len64(u) - bits.TrailingZeros64(u); n > max {
+			max = n
+		}
+	}
+	return max
+}
@@ -0,0 +1,736 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"testing"
+)
+
+func randomSlice(n int) []float64 {
+	s := make([]float64, n)
+	state := uint64(1)
+	for i := range s {
+		s[i] = RandomFloat64(&state)
+	}
+	return s
+}
+
+func BenchmarkProduct(b *testing.B) {
+	s := randomSlice(1000)
+	var y float64
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		y = Product(s)
+	}
+	fsink = y
+}
+
+func BenchmarkSortAndCluster(b *testing.B) {
+	r := randomSlice(1000)
+	s := make([]float64, len(r))
+	var c [][]float64
+	for n := 0; n < b.N; n++ {
+		copy(s, r)
+		c = SortAndCluster(s, 1<<60)
+	}
+	isink = len(c)
+}
+
+func BenchmarkAlmostEqualSlice(b *testing.B) {
+	x := randomSlice(1000)
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = NextFromZero(x[i])
+	}
+	var is bool
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		is, _ = AlmostEqualSlice(x, y, 1)
+	}
+	bsink = is
+}
+
+func BenchmarkCompoundProduct(b *testing.B) {
+	s := randomSlice(1000)
+	for i := range s {
+		s[i] = math.Ldexp(s[i], -Log2(s[i]) - 20)
+	}
+	var y float64
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		y = CompoundProduct(s)
+	}
+	fsink = y
+}
+
+func BenchmarkMeanPairwise(b *testing.B) {
+	s := randomSlice(1000)
+	var y float64
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		y = MeanPairwise(s)
+	}
+	fsink = y
+}
+
+func BenchmarkSumOrderIndependent(b *testing.B) {
+	s := randomSlice(1000)
+	var is bool
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		is = SumOrderIndependent(s, 1)
+	}
+	bsink = is
+}
+
+func BenchmarkUlpHausdorff(b *testing.B) {
+	x := randomSlice(1000)
+	sort.Float64s(x)
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = NextFromZero(x[i])
+	}
+	var u uint64
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		u = UlpHausdorff(x, y)
+	}
+	usink = u
+}
+
+func BenchmarkNearestFloat(b *testing.B) {
+	s := AdjacentSequence(1, 1000)
+	var i int
+	for n := 0; n < b.N; n++ {
+		_, i, _ = NearestFloat(s, 1+float64(n%1000)*0x1p-52)
+	}
+	isink = i
+}
+
+func BenchmarkUlpsBetweenStrided(b *testing.B) {
+	x := randomSlice(3000)
+	y := append([]float64(nil), x...)
+	var u uint64
+	for n := 0; n < b.N; n++ {
+		u, _ = UlpsBetweenStrided(x[1:], y[1:], 3, 3, 1000)
+	}
+	usink = u
+}
+
+func BenchmarkScaleAndClampSlice(b *testing.B) {
+	s := randomSlice(1000)
+	for n := 0; n < b.N; n++ {
+		ScaleAndClampSlice(s, -1, -1e300, 1e300)
+	}
+	fsink = s[0]
+}
+
+func BenchmarkScaleAndClampSliceTwoPasses(b *testing.B) {
+	s := randomSlice(1000)
+	for n := 0; n < b.N; n++ {
+		for i := range s {
+			s[i] *= -1
+		}
+		for i := range s {
+			s[i] = math.Max(-1e300, math.Min(s[i], 1e300))
+		}
+	}
+	fsink = s[0]
+}
+
+func BenchmarkDedupeByUlps(b *testing.B) {
+	s := AdjacentSequence(1, 1000)
+	t := make([]float64, len(s))
+	var d []float64
+	for n := 0; n < b.N; n++ {
+		copy(t, s)
+		d = DedupeByUlps(t, 4)
+	}
+	fsink = d[0]
+}
+
+func BenchmarkMinBitsForSlice(b *testing.B) {
+	s := randomSlice(1000)
+	var k int
+	for n := 0; n < b.N; n++ {
+		k = MinBitsForSlice(s)
+	}
+	isink = k
+}
+
+// ------------------------------------------------------------- Tests
+func TestProduct(t *testing.T) {
+	inf, nan := math.Inf(1), math.NaN()
+	s := []float64{1e300, 1e300, -1e-300, 3, 1e-300}
+	naive := 1.0
+	for _, x := range s {
+		naive *= x
+	}
+	p := Product(s)
+	t.Logf("naive        %v", naive)
+	t.Logf("Product      %v", p)
+	if UlpsBetween(p, -3) > 2 {
+		t.Fatalf("Product %v, want -3", p)
+	}
+	cases := []struct {
+		s    []float64
+		want float64
+	}{
+		{nil, 1},
+		{[]float64{0x1p-600, 0x1p-600, 0x1p+1000}, 0x1p-200},
+		{[]float64{0x1p+1000, 0x1p+1000, -0x1p-1000}, -0x1p+1000},
+		{[]float64{0x1p+1000, 0x1p+100}, inf},
+		{[]float64{-0x1p-1000, 0x1p-100}, math.Copysign(0, -1)},
+		{[]float64{0x1p-1000, 0x1p-70}, 0x1p-1070},
+		{[]float64{2, inf, -1}, -inf},
+	}
+	for _, c := range cases {
+		p := Product(c.s)
+		t.Logf("%v  %v", c.s, p)
+		if math.Float64bits(p) != math.Float64bits(c.want) {
+			t.Fatalf("Product(%v) = %v, want %v", c.s, p, c.want)
+		}
+	}
+	if p := Product([]float64{1, nan, 0x1p+1000}); p == p {
+		t.Fatalf("Product with NaN %v", p)
+	}
+	if p := Product([]float64{inf, 0}); p == p {
+		t.Fatalf("Product(Inf, 0) %v", p)
+	}
+}
+
+func TestSortAndCluster(t *testing.T) {
+	nan := math.NaN()
+	s := []float64{2, 1, NextFromZero(2), nan, NextToZero(1), NextToZero(NextToZero(2)), 1}
+	c := SortAndCluster(s, 2)
+	t.Logf("%v", c)
+	if len(c) != 3 || len(c[0]) != 3 || len(c[1]) != 3 || c[2][0] == c[2][0] {
+		t.Fatalf("clusters %v", c)
+	}
+	if c[0][0] != NextToZero(1) || c[1][2] != NextFromZero(2) {
+		t.Fatalf("order %v", c)
+	}
+	c = SortAndCluster([]float64{0, math.Copysign(0, -1), -0x1p-1074}, 0)
+	if len(c) != 2 || !math.Signbit(c[1][0]) || math.Signbit(c[1][1]) {
+		t.Fatalf("zeros %v", c)
+	}
+	if SortAndCluster(nil, 1) != nil {
+		t.Fatalf("SortAndCluster(nil) not nil")
+	}
+}
+
+func TestAlmostEqualSlice(t *testing.T) {
+	x := randomSlice(100)
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = NextToZero(NextToZero(x[i]))
+	}
+	if ok, i := AlmostEqualSlice(x, y, 2); !ok || i != -1 {
+		t.Fatalf("2 ulps: %v %d", ok, i)
+	}
+	if ok, i := AlmostEqualSlice(x, y, 1); ok || i != 0 {
+		t.Fatalf("1 ulp: %v %d", ok, i)
+	}
+	copy(y, x)
+	y[40] = NextFromZero(NextFromZero(y[40]))
+	y[70] = math.NaN()
+	if ok, i := AlmostEqualSlice(x, y, 1); ok || i != 40 {
+		t.Fatalf("index 40: %v %d", ok, i)
+	}
+	if ok, i := AlmostEqualSlice(x, y, 2); ok || i != 70 {
+		t.Fatalf("NaN: %v %d", ok, i)
+	}
+	if ok, i := AlmostEqualSlice(x, x[:50], 0); ok || i != 50 {
+		t.Fatalf("lengths: %v %d", ok, i)
+	}
+	if ok, i := AlmostEqualSlice(nil, []float64{}, 0); !ok || i != -1 {
+		t.Fatalf("empty: %v %d", ok, i)
+	}
+}
+
+func TestCompoundProduct(t *testing.T) {
+	const n = 100000
+	rates := make([]float64, n)
+	state := uint64(1)
+	ref := new(big.Float).SetPrec(2000).SetInt64(1)
+	f := new(big.Float).SetPrec(2000)
+	naive := 1.0
+	for i := range rates {
+		rates[i] = (float64(Splitmix(&state) >> 11) * 0x1p-53 - 0.3) * 1e-5
+		naive *= 1 + rates[i]
+		f.SetFloat64(rates[i])
+		ref.Mul(ref, f.Add(f, big.NewFloat(1)))
+	}
+	want, _ := ref.Float64()
+	p := CompoundProduct(rates)
+	t.Logf("reference        %v", want)
+	t.Logf("CompoundProduct  %v  (%d ulps)", p, UlpsBetween(p, want))
+	t.Logf("naive            %v  (%d ulps)", naive, UlpsBetween(naive, want))
+	if UlpsBetween(p, want) > 4 {
+		t.Fatalf("CompoundProduct %v, want %v", p, want)
+	}
+	inf, nan := math.Inf(1), math.NaN()
+	cases := []struct {
+		rates []float64
+		want  float64
+	}{
+		{nil, 1},
+		{[]float64{1, 1, 1}, 8},
+		{[]float64{-3, 0.5}, -3},
+		{[]float64{-3, -3}, 4},
+		{[]float64{0.5, -1}, 0},
+		{[]float64{inf, 2}, inf},
+		{[]float64{-inf, 2}, -inf},
+	}
+	for _, c := range cases {
+		if p := CompoundProduct(c.rates); UlpsBetween(p, c.want) > 2 {
+			t.Fatalf("CompoundProduct(%v) = %v, want %v", c.rates, p, c.want)
+		}
+	}
+	for _, r := range [][]float64{{1, nan}, {inf, -1}} {
+		if p := CompoundProduct(r); p == p {
+			t.Fatalf("CompoundProduct(%v) = %v, want NaN", r, p)
+		}
+	}
+}
+
+func TestAdjacentSequence(t *testing.T) {
+	starts := []float64{1, -1, NextToZero(2), -3 * 0x1p-1074, 0, math.Copysign(0, -1), -0x1p-1022}
+	for _, start := range starts {
+		s := AdjacentSequence(start, 10)
+		if len(s) != 10 || s[0] != start {
+			t.Fatalf("start %v: %v", start, s)
+		}
+		for i := 1; i < len(s); i++ {
+			if !AdjacentFP(s[i-1], s[i]) || UlpsBetween(s[i-1], s[i]) != 1 || !(s[i-1] < s[i]) {
+				t.Fatalf("start %v: %v and %v not adjacent", start, s[i-1], s[i])
+			}
+			if !Adjacent(s[i-1], s[i]) && !(s[i-1] == 0 && s[i] == 0x1p-1074) {
+				t.Fatalf("start %v: Adjacent(%v, %v) false", start, s[i-1], s[i])
+			}
+		}
+	}
+	s := AdjacentSequence(NextToZero(math.MaxFloat64), 4)
+	if s[2] != math.Inf(1) || s[3] != math.Inf(1) {
+		t.Fatalf("saturation %v", s)
+	}
+	if AdjacentSequence(1, 0) != nil || len(AdjacentSequence(1, 1)) != 1 {
+		t.Fatalf("n <= 1")
+	}
+}
+
+func TestMeanPairwise(t *testing.T) {
+	const n = 1 << 20
+	s := make([]float64, n)
+	state := uint64(1)
+	exact := new(big.Float).SetPrec(200)
+	naive := 0.0
+	for i := range s {
+		s[i] = 0.1 + float64(Splitmix(&state)>>11)*0x1p-60
+		naive += s[i]
+		exact.Add(exact, big.NewFloat(s[i]))
+	}
+	exact.Quo(exact, big.NewFloat(n))
+	want, _ := exact.Float64()
+	naive /= n
+	mean := MeanPairwise(s)
+	t.Logf("exact     %v", want)
+	t.Logf("pairwise  %v  %d ulps", mean, UlpsBetween(mean, want))
+	t.Logf("naive     %v  %d ulps", naive, UlpsBetween(naive, want))
+	if UlpsBetween(mean, want) > 2 || UlpsBetween(mean, want) >= UlpsBetween(naive, want) {
+		t.Fatalf("pairwise %v, naive %v, exact %v", mean, naive, want)
+	}
+	huge := []float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64 / 2}
+	if m := MeanPairwise(huge); UlpsBetween(m, math.MaxFloat64/6*5) > 2 {
+		t.Fatalf("overflow: %v", m)
+	}
+	mixed := make([]float64, 18)                 // halves overflow to +Inf and -Inf
+	for i := range mixed {
+		mixed[i] = math.MaxFloat64
+		if i >= 9 {
+			mixed[i] = -math.MaxFloat64
+		}
+	}
+	if m := MeanPairwise(mixed); m != 0 {
+		t.Fatalf("mixed sign overflow: %v", m)
+	}
+	mixed[17] = -math.MaxFloat64 / 2
+	if m := MeanPairwise(mixed); UlpsBetween(m, math.MaxFloat64/36) > 2 {
+		t.Fatalf("mixed sign overflow: %v, want %v", m, math.MaxFloat64/36)
+	}
+	inf := math.Inf(1)
+	if !IsNaN(MeanPairwise(nil)) || !IsNaN(MeanPairwise([]float64{1, math.NaN()})) ||
+		!IsNaN(MeanPairwise([]float64{inf, -inf})) || MeanPairwise([]float64{inf, math.MaxFloat64}) != inf ||
+		MeanPairwise([]float64{-3}) != -3 {
+		t.Fatalf("special cases")
+	}
+}
+
+func TestResampleUniformUlps(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		lo, hi float64
+		n      int
+	}{
+		{1, 2, 5},
+		{1, 2, 1000},
+		{-1, 1, 7},
+		{-inf, inf, 100},
+		{0x1p-1074, 1, 3},
+		{1, NextFromZero(NextFromZero(1)), 5},      // fewer ulps than n
+		{3, 3, 4},
+		{2, -2, 6},                                  // decreasing
+	}
+	for _, c := range cases {
+		g := ResampleUniformUlps([]float64{c.lo, (c.lo + c.hi) / 2, c.hi}, c.n)
+		if len(g) != c.n || g[0] != c.lo || g[c.n-1] != c.hi {
+			t.Fatalf("%v %v %d: %v", c.lo, c.hi, c.n, g)
+		}
+		step := UlpsBetween(c.lo, c.hi) / uint64(c.n-1)
+		for i := 1; i < len(g); i++ {
+			d := UlpsBetween(g[i-1], g[i])
+			if d != step && d != step+1 || (c.lo <= c.hi) != (g[i-1] <= g[i]) {
+				t.Fatalf("%v %v %d: %v and %v, %d ulps, step %d", c.lo, c.hi, c.n, g[i-1], g[i], d, step)
+			}
+			if UlpsBetween(c.lo, c.hi)%uint64(c.n-1) == 0 && d != step {
+				t.Fatalf("%v %v %d: not equidistant", c.lo, c.hi, c.n)
+			}
+		}
+		if c.n == 5 && c.lo == 1 && c.hi == 2 {
+			t.Logf("%v", g)
+		}
+	}
+	s := []float64{1, 2}
+	if ResampleUniformUlps(s, 0) != nil || ResampleUniformUlps(nil, 3) != nil ||
+		ResampleUniformUlps([]float64{math.NaN(), 1}, 3) != nil ||
+		len(ResampleUniformUlps(s, 1)) != 1 || ResampleUniformUlps(s, 1)[0] != 1 {
+		t.Fatalf("special cases")
+	}
+}
+
+func TestSumOrderIndependent(t *testing.T) {
+	s := []float64{1, 0x1p-53, 0x1p-53, 0x1p-53, 0x1p-53}    // 1 + 2^-51 from the right, 1 from the left
+	fwd, bwd := 0.0, 0.0
+	for i := range s {
+		fwd += s[i]
+		bwd += s[len(s)-1-i]
+	}
+	t.Logf("left %v, right %v, %d ulps", fwd, bwd, UlpsBetween(fwd, bwd))
+	if SumOrderIndependent(s, 0) || SumOrderIndependent(s, 1) || !SumOrderIndependent(s, 2) {
+		t.Fatalf("order sensitive slice")
+	}
+	ints := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	inf := math.Inf(1)
+	if !SumOrderIndependent(ints, 0) || !SumOrderIndependent(nil, 0) ||
+		SumOrderIndependent([]float64{1, math.NaN()}, 1<<62) || !SumOrderIndependent([]float64{1, math.NaN()}, maxUint64) ||
+		SumOrderIndependent([]float64{inf, -inf}, 1<<62) || !SumOrderIndependent([]float64{inf, 1}, 0) ||
+		SumOrderIndependent([]float64{math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}, 1<<62) {
+		t.Fatalf("special cases")
+	}
+}
+
+func TestUlpHausdorff(t *testing.T) {
+	const rounds int = 1e4
+	a := ResampleUniformUlps([]float64{1, 0x1p20}, 1001)
+	b := make([]float64, len(a))
+	for i, x := range a {
+		b[i] = fromOrdinal(ordinal(x) + 12345)
+	}
+	t.Logf("grid step %d, shift 12345, Hausdorff %d", UlpsBetween(a[0], a[1]), UlpHausdorff(a, b))
+	if h := UlpHausdorff(a, b); h != 12345 || UlpHausdorff(b, a) != h {
+		t.Fatalf("shifted grids: %d", h)
+	}
+	b = append(b, 0x1p21)
+	if h := UlpHausdorff(a, b); h != UlpsBetween(0x1p20, 0x1p21) {
+		t.Fatalf("extra point: %d", h)
+	}
+	// brute force
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		a := make([]float64, 1+Splitmix(&state)%10)
+		b := make([]float64, 1+Splitmix(&state)%10)
+		for j := range a {
+			a[j] = RandomFloat64(&state)
+		}
+		for j := range b {
+			b[j] = fromOrdinal(ordinal(a[0]) + int64(Splitmix(&state)>>40) - 1<<23)
+		}
+		sort.Float64s(a)
+		sort.Float64s(b)
+		var want uint64
+		for _, s := range [][2][]float64{{a, b}, {b, a}} {
+			for _, x := range s[0] {
+				min := uint64(maxUint64)
+				for _, y := range s[1] {
+					if d := UlpsBetween(x, y); d < min {
+						min = d
+					}
+				}
+				if min > want {
+					want = min
+				}
+			}
+		}
+		if h := UlpHausdorff(a, b); h != want {
+			t.Fatalf("a %v\nb %v\n%d, want %d", a, b, h, want)
+		}
+	}
+	if UlpHausdorff(nil, nil) != 0 || UlpHausdorff(a, nil) != maxUint64 ||
+		UlpHausdorff([]float64{1, math.NaN()}, []float64{1}) != maxUint64 ||
+		UlpHausdorff([]float64{math.Copysign(0, -1)}, []float64{0}) != 0 {
+		t.Fatalf("special cases")
+	}
+}
+
+func TestSuggestUlpThreshold(t *testing.T) {
+	var s []float64
+	for i := 0; i < 50; i++ {
+		s = append(s, AdjacentSequence(1+0x1p-40*float64(i), 3)...)     // cluster near 1
+	}
+	for i := 0; i < 30; i++ {
+		s = append(s, 1e6+float64(i)*1e-9)                               // cluster near 1e6
+	}
+	state := uint64(1)
+	for i := len(s) - 1; i > 0; i-- {
+		j := int(Splitmix(&state) % uint64(i+1))
+		s[i], s[j] = s[j], s[i]
+	}
+	th := SuggestUlpThreshold(s)
+	c := SortAndCluster(append([]float64(nil), s...), th)
+	t.Logf("threshold %d  clusters %d", th, len(c))
+	if len(c) != 2 || len(c[0]) != 150 || len(c[1]) != 30 {
+		t.Fatalf("SuggestUlpThreshold = %d gives %d clusters", th, len(c))
+	}
+	if th = SuggestUlpThreshold([]float64{2, 2, 2}); th != 0 {
+		t.Fatalf("all equal: %d", th)
+	}
+	if th = SuggestUlpThreshold(AdjacentSequence(1, 100)); th != 1 {
+		t.Fatalf("uniform: %d", th)
+	}
+	if th = SuggestUlpThreshold([]float64{1}); th != 0 {
+		t.Fatalf("one element: %d", th)
+	}
+}
+
+func TestNearestFloat(t *testing.T) {
+	const rounds int = 1e5
+	g := ResampleUniformUlps([]float64{-1e10, 1e10}, 1001)
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		if i%2 == 0 {
+			x = (float64(Splitmix(&state)>>11)*0x1p-52 - 1) * 1.1e10
+		}
+		v, j, d := NearestFloat(g, x)
+		best := uint64(maxUint64)
+		for _, y := range g {
+			if e := UlpsBetween(y, x); e < best {
+				best = e
+			}
+		}
+		if x != x {
+			best = maxUint64
+		}
+		if d != best || x == x && (g[j] != v || UlpsBetween(v, x) != d) {
+			t.Fatalf("NearestFloat(%v) = %v, %d, %d, want %d ulps", x, v, j, d, best)
+		}
+	}
+	v, j, d := NearestFloat([]float64{-0x1p-1074, 0x1p-1074}, 0)
+	t.Logf("tie        %v %d %d", v, j, d)
+	if j != 0 {
+		t.Fatalf("tie: index %d", j)
+	}
+	if v, j, d = NearestFloat([]float64{math.Copysign(0, -1)}, 0); j != 0 || d != 0 || !math.Signbit(v) {
+		t.Fatalf("zero: %v %d %d", v, j, d)
+	}
+	if v, j, d = NearestFloat(nil, 1); v == v || j != -1 || d != maxUint64 {
+		t.Fatalf("nil: %v %d %d", v, j, d)
+	}
+	if v, j, d = NearestFloat(g, math.NaN()); v == v || j != -1 || d != maxUint64 {
+		t.Fatalf("NaN: %v %d %d", v, j, d)
+	}
+	if v, j, d = NearestFloat([]float64{math.NaN()}, 1); v == v || j != 0 || d != maxUint64 {
+		t.Fatalf("NaNs: %v %d %d", v, j, d)
+	}
+}
+
+func TestUlpsBetweenStrided(t *testing.T) {
+	// records {x, y, z} interleaved and the y field as a plain slice
+	const n = 100
+	aos := make([]float64, 3*n)
+	ys := make([]float64, n)
+	for i := 0; i < n; i++ {
+		aos[3*i], aos[3*i+1], aos[3*i+2] = float64(i), 1+float64(i)/n, -float64(i)
+		ys[i] = aos[3*i+1]
+	}
+	d, i := UlpsBetweenStrided(aos[1:], ys, 3, 1, n)
+	t.Logf("equal        %d %d", d, i)
+	if d != 0 || i != 0 {
+		t.Fatalf("equal: %d %d", d, i)
+	}
+	ys[40] = NextFromZero(NextFromZero(ys[40]))
+	ys[70] = NextToZero(NextToZero(ys[70]))
+	ys[10] = NextFromZero(ys[10])
+	if d, i = UlpsBetweenStrided(aos[1:], ys, 3, 1, n); d != 2 || i != 40 {
+		t.Fatalf("worst: %d %d", d, i)
+	}
+	if d, i = UlpsBetweenStrided(ys, aos[1:], 1, 3, 30); d != 1 || i != 10 {
+		t.Fatalf("first 30: %d %d", d, i)
+	}
+	ys[90] = math.NaN()
+	if d, i = UlpsBetweenStrided(aos[1:], ys, 3, 1, n); d != maxUint64 || i != 90 {
+		t.Fatalf("NaN: %d %d", d, i)
+	}
+	bad := []struct{ sx, sy, n int }{
+		{3, 1, n + 1},
+		{4, 1, n},
+		{0, 1, 1},
+		{3, -1, 2},
+		{3, 1, -1},
+		{1 << 62, 1, 3},
+	}
+	for _, c := range bad {
+		if d, i = UlpsBetweenStrided(aos[1:], ys, c.sx, c.sy, c.n); d != maxUint64 || i != -1 {
+			t.Fatalf("%v: %d %d", c, d, i)
+		}
+	}
+	if d, i = UlpsBetweenStrided(nil, nil, 1, 1, 0); d != 0 || i != -1 {
+		t.Fatalf("n = 0: %d %d", d, i)
+	}
+}
+
+func TestScaleAndClampSlice(t *testing.T) {
+	inf, nan := math.Inf(1), math.NaN()
+	s := []float64{0, 0.5, -0.5, 2, -3, inf, -inf, nan, 1e308, math.Copysign(0, -1)}
+	want := []float64{0, 1, -1, 1, -1, 1, -1, nan, 1, 0}
+	ScaleAndClampSlice(s, 2, -1, 1)
+	for i := range s {
+		if s[i] != want[i] && !(s[i] != s[i] && want[i] != want[i]) {
+			t.Fatalf("%d: %v, want %v", i, s[i], want[i])
+		}
+	}
+	if !math.Signbit(s[len(s)-1]) {
+		t.Fatalf("-0 * 2 = %v", s[len(s)-1])
+	}
+	s = []float64{0, 1, -1}
+	ScaleAndClampSlice(s, inf, -10, 10)
+	if s[0] == s[0] || s[1] != 10 || s[2] != -10 {
+		t.Fatalf("0 * Inf: %v", s)
+	}
+	s = []float64{5, -5}
+	ScaleAndClampSlice(s, 1, nan, 1)
+	if s[0] != 1 || s[1] != -5 {
+		t.Fatalf("NaN lo: %v", s)
+	}
+	state := uint64(1)
+	x := randomSlice(1000)
+	y := append([]float64(nil), x...)
+	scale := RandomFloat64(&state)
+	ScaleAndClampSlice(y, scale, -1e10, 1e10)
+	for i := range x {
+		if w := math.Max(-1e10, math.Min(x[i]*scale, 1e10)); y[i] != w && y[i] == y[i] {
+			t.Fatalf("%v * %v = %v, want %v", x[i], scale, y[i], w)
+		}
+	}
+}
+
+func TestDedupeByUlps(t *testing.T) {
+	nan := math.NaN()
+	up := func(x float64, n int) float64 {
+		for ; n > 0; n-- {
+			x = NextFromZero(x)
+		}
+		return x
+	}
+	s := []float64{
+		-nan, -1, math.Copysign(0, -1), 0, 0x1p-1074,
+		1, up(1, 1), up(1, 2), up(1, 3), up(1, 5), 2, 2, up(2, 1), nan, nan,
+	}
+	cases := []struct {
+		maxUlps uint64
+		want    []float64
+	}{
+		{0, []float64{-nan, -1, math.Copysign(0, -1), 0x1p-1074, 1, up(1, 1), up(1, 2), up(1, 3), up(1, 5), 2, up(2, 1), nan, nan}},
+		{1, []float64{-nan, -1, math.Copysign(0, -1), 1, up(1, 2), up(1, 5), 2, nan, nan}},
+		{3, []float64{-nan, -1, math.Copysign(0, -1), 1, up(1, 5), 2, nan, nan}},
+		{maxUint64, []float64{-nan}},
+	}
+	for _, c := range cases {
+		d := DedupeByUlps(append([]float64(nil), s...), c.maxUlps)
+		t.Logf("%d  %v", c.maxUlps, d)
+		if len(d) != len(c.want) {
+			t.Fatalf("maxUlps %d: %v, want %v", c.maxUlps, d, c.want)
+		}
+		for i := range d {
+			if math.Float64bits(d[i]) != math.Float64bits(c.want[i]) {
+				t.Fatalf("maxUlps %d: %v, want %v", c.maxUlps, d, c.want)
+			}
+		}
+	}
+	if d := DedupeByUlps(nil, 1); len(d) != 0 {
+		t.Fatalf("nil: %v", d)
+	}
+	// Kept values are more than maxUlps apart and every value is near a kept one.
+	x := randomSlice(2000)
+	for i := range x {
+		x[i] = math.Float64frombits(math.Float64bits(x[i])&^(1<<52-1) | uint64(i%7))
+	}
+	sort.Slice(x, func(i, j int) bool { return totalLess(x[i], x[j]) })
+	d := DedupeByUlps(append([]float64(nil), x...), 4)
+	for i := 1; i < len(d); i++ {
+		if UlpsBetween(d[i-1], d[i]) <= 4 {
+			t.Fatalf("kept %v %v", d[i-1], d[i])
+		}
+	}
+	for _, v := range x {
+		if _, _, u := NearestFloat(d, v); u > 4 && v == v {
+			t.Fatalf("%v is %d ulps from the kept values", v, u)
+		}
+	}
+}
+
+func TestMinBitsForSlice(t *testing.T) {
+	cases := []struct {
+		s    []float64
+		want int
+	}{
+		{nil, 0},
+		{[]float64{0, math.Copysign(0, -1)}, 0},
+		{[]float64{1, -2, 0x1p-1074, 0x1p1023}, 1},
+		{[]float64{1.5, -0.75, 3}, 2},
+		{[]float64{0.5, 0.25, 1.125, 100}, 5},          // 1.125 = 0b1.001, 100 = 0b1100100
+		{[]float64{1 + 0x1p-23}, 24},
+		{[]float64{1 + 0x1p-24}, 25},
+		{[]float64{0.1}, 52},                           // 0x3fb999999999999a
+		{[]float64{1 + 0x1p-52}, 53},
+		{[]float64{3 * 0x1p-1074}, 2},
+		{[]float64{1, math.Inf(1)}, 53},
+		{[]float64{math.NaN()}, 53},
+		{[]float64{math.MaxFloat64}, 53},
+	}
+	for _, c := range cases {
+		k := MinBitsForSlice(c.s)
+		t.Logf("%v  %d", c.s, k)
+		if k != c.want {
+			t.Fatalf("MinBitsForSlice(%v) = %d, want %d", c.s, k, c.want)
+		}
+	}
+	// Values rounded to float32 and to 8 bits.
+	x := randomSlice(1000)
+	f := make([]float64, len(x))
+	g := make([]float64, len(x))
+	for i := range x {
+		if f[i] = float64(float32(x[i])); IsInf(f[i]) {
+			f[i] = 1
+		}
+		m, e := math.Frexp(x[i])
+		g[i] = math.Ldexp(math.Round(m*256)/256, e)
+	}
+	if k := MinBitsForSlice(f); k > 24 {
+		t.Fatalf("float32 values: %d bits", k)
+	}
+	if k := MinBitsForSlice(g); k > 8 {
+		t.Fatalf("8 bit values: %d bits", k)
+	}
+}
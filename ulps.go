@@ -0,0 +1,334 @@
+package fbits
+
+import (
+	"math"
+	"math/big"
+)
+
+// DriftTracker accumulates the ulp distances between successive values of
+// an iteration. The zero value is ready to use.
+//
+// The total is a float64, so it doesn't overflow as an uint64 sum would.
+// A NaN value adds UlpsBetween(x, NaN) = maxUint64 (~1.8e19) to the total
+// both when it is added and when the next value is added.
+//
+type DriftTracker struct {
+	last    float64
+	total   float64
+	started bool
+}
+
+// Add adds UlpsBetween(previous value, x) to the total and makes x
+// the previous value. The first call only sets the previous value.
+func (d *DriftTracker) Add(x float64) {
+	if d.started {
+		d.total += float64(UlpsBetween(d.last, x))
+	}
+	d.last = x
+	d.started = true
+}
+
+// TotalUlps returns the sum of the ulp distances between successive values.
+func (d *DriftTracker) TotalUlps() float64 {
+	return d.total
+}
+
+// UlpsForAbsTol returns how many ulps of at the absolute tolerance absTol
+// spans, floor(absTol / Ulp(at)).
+//
+// This translates an absolute tolerance at magnitude at into an ulp tolerance
+// for UlpsBetween. Ulp(at) is the ulp away from zero, at a power of two the
+// ulps towards zero are half of that.
+// Special cases:
+// UlpsForAbsTol(0, absTol)      = absTol / 2^-1074, saturated to maxUint64
+// UlpsForAbsTol(at, absTol < 0) = 0
+// UlpsForAbsTol(at, +Inf)       = maxUint64
+// UlpsForAbsTol(+/-Inf, absTol) = 0        absTol finite
+// UlpsForAbsTol(NaN, absTol)    = 0
+// UlpsForAbsTol(at, NaN)        = 0
+//
+func UlpsForAbsTol(at, absTol float64) uint64 {
+	q := absTol / Ulp(at)          // exact, unless q is subnormal
+	switch {
+	case !(q >= 1):                // negative, less than one ulp or NaN
+		return 0
+	case q >= 0x1p64:
+		return maxUint64
+	}
+	return uint64(q)               // conversion truncates towards zero
+}
+
+// IsApproxInverse returns true if x*y is at most maxUlps ulps from 1.
+//
+// This is for checking reciprocals and inverses with a tolerance.
+// x*y is rounded before the comparison, which can add 1 ulp.
+// If x or y is zero, Inf or NaN, IsApproxInverse returns false, also
+// for a large maxUlps. A finite nonzero x*y near 1 can't overflow or underflow.
+//
+func IsApproxInverse(x, y float64, maxUlps uint64) bool {
+	if x == 0 || y == 0 || !IsFinite(x) || !IsFinite(y) {
+		return false
+	}
+	return UlpsBetween(x*y, 1) <= maxUlps
+}
+
+// SubUlps returns the difference x - y in ulps of the operand with the larger
+// magnitude, rounded towards zero, and true if the float64 subtraction
+// x - y is exact and the difference is a whole number of these ulps.
+//
+// If x and y have the same ulp, eg. they are in the same binade, ulps is
+// exact, and for the same sign it is also UlpsBetween(y, x) with a sign.
+// exact is then false only if abs(x - y) >= 2^53 ulps and x - y rounds
+// or overflows, which happens for opposite signs.
+// Across binades the ulp changes mid-difference. The smaller operand has a
+// finer ulp and the difference is generally not a whole number of the larger
+// ulps, so exact is false. Cross-binade differences are done with big.Rat.
+// Special cases:
+// SubUlps(x, x)             = 0, true
+// SubUlps(2, NextToZero(2)) = 0, false    2 - (2 - 2^-52) = 1/2 ulp(2)
+// SubUlps(x, +/-Inf)        = 0, false
+// SubUlps(x, NaN)           = 0, false
+//
+func SubUlps(x, y float64) (ulps int64, exact bool) {
+	if !IsFinite(x) || !IsFinite(y) {
+		return 0, false
+	}
+	u := Ulp(x)
+	if uy := Ulp(y); uy == u {
+		d := int64(x/u) - int64(y/u)           // x/u and y/u are exact integers < 2^53
+		return d, (d & 1 == 0 || -1<<53 < d && d < 1<<53) && !IsInf(x - y)
+	} else if uy > u {
+		u = uy
+	}
+	diff := new(big.Rat).SetFloat64(x)
+	diff.Sub(diff, new(big.Rat).SetFloat64(y))
+	d := x - y
+	exact = !IsInf(d) && new(big.Rat).SetFloat64(d).Cmp(diff) == 0
+	r := diff.Quo(diff, new(big.Rat).SetFloat64(u))
+	q := new(big.Int).Quo(r.Num(), r.Denom())   // truncated towards zero
+	exact = exact && r.IsInt()
+	return q.Int64(), exact
+}
+
+// InUlpNeighborhood returns true if x is at most radiusUlps ulps from center,
+// UlpsBetween(center, x) <= radiusUlps.
+//
+// The neighbourhood is a symmetric window of 2*radiusUlps + 1 floats in the
+// order of the float64 values. Around zero it crosses over to the other sign,
+// +0 and -0 are the same point: the neighbourhood of 0 with radius 1 is
+// {-2^-1074, +/-0, 2^-1074}. The window ends at +/-Inf, UlpsBetween(Inf,
+// MaxFloat64) = 1. If center or x is NaN, InUlpNeighborhood returns false.
+//
+func InUlpNeighborhood(center, x float64, radiusUlps uint64) bool {
+	return UlpsBetween(center, x) <= radiusUlps && !IsNaN(center) && !IsNaN(x)
+}
+
+// UlpsBetweenMagnitude returns the distance between abs(x) and abs(y) in
+// ulps, UlpsBetween(abs(x), abs(y)).
+//
+// The signs are ignored, UlpsBetweenMagnitude(-1, 1) = 0, where
+// UlpsBetween(-1, 1) counts all the floats between -1 and 1.
+// Special cases:
+// UlpsBetweenMagnitude(x, NaN)       = maxUint64
+// UlpsBetweenMagnitude(-Inf, +Inf)   = 0
+//
+func UlpsBetweenMagnitude(x, y float64) uint64 {
+	a := math.Float64bits(x) &^ signbit
+	b := math.Float64bits(y) &^ signbit
+	switch {
+	case a > posInf || b > posInf:
+		return maxUint64
+	case a > b:
+		return a - b
+	}
+	return b - a
+}
+
+// DampedNewtonStep returns x + delta, but at most maxUlps ulps from x.
+//
+// If x + delta is more than maxUlps ulps from x, the result is the float
+// maxUlps ulps from x in the direction of delta, counted in the order of
+// the float64 values. The step can cross zero, +0 and -0 are one point,
+// and stops at +/-Inf. A delta within maxUlps passes through as x + delta.
+// Special cases:
+// DampedNewtonStep(x, 0, n)      = x
+// DampedNewtonStep(x, NaN, n)    = NaN
+// DampedNewtonStep(NaN, d, n)    = NaN
+// DampedNewtonStep(x, d, 0)      = x
+//
+func DampedNewtonStep(x, delta float64, maxUlps uint64) float64 {
+	y := x + delta
+	if y != y || UlpsBetween(x, y) <= maxUlps {
+		return y
+	}
+	const inf = posInf
+	k := ordinal(x)
+	if delta > 0 {
+		if maxUlps >= uint64(inf-k) {
+			return math.Inf(1)
+		}
+		return fromOrdinal(k + int64(maxUlps))
+	}
+	if maxUlps >= uint64(k+inf) {
+		return math.Inf(-1)
+	}
+	return fromOrdinal(k - int64(maxUlps))
+}
+
+// FindFlip returns the last float x in [lo, hi] with pred(x) false, for
+// a pred which is false at lo and true at hi. The float after x is true.
+//
+// The search halves the interval in the order of the float64 values,
+// in ordinals, so it takes at most 64 steps for any lo and hi, also across
+// zero and over many binades. For a pred which is not monotone, the result
+// is some x with pred(x) false and pred(next x) true, not necessarily the
+// first or the last one. +0 and -0 are one point.
+// Special cases:
+// FindFlip(lo, hi, pred)   = NaN    pred(lo) true or pred(hi) false
+// FindFlip(NaN, hi, pred)  = NaN
+// FindFlip(lo, hi, pred)   = NaN    lo > hi
+//
+//	x := FindFlip(1, 2, func(x float64) bool { return x*x >= 2 })
+//
+func FindFlip(lo, hi float64, pred func(float64) bool) float64 {
+	a, b := ordinal(lo), ordinal(hi)
+	if lo != lo || hi != hi || a > b || pred(lo) || !pred(hi) {
+		return math.NaN()
+	}
+	for uint64(b-a) > 1 {
+		m := a + int64(uint64(b-a)/2)
+		if pred(fromOrdinal(m)) {
+			b = m
+		} else {
+			a = m
+		}
+	}
+	if a == ordinal(lo) {
+		return lo
+	}
+	return fromOrdinal(a)
+}
+
+// DiffFromOne returns x - 1.
+//
+// For 0.5 <= x <= 2 the subtraction is exact by the Sterbenz lemma, so
+// DiffFromOne(x) is the exact distance of x from unity also when x is a few
+// ulps from 1. Outside that range x - 1 is rounded once. To get x - 1
+// accurately from a computed ratio, compute the ratio, not 1 + small, as a
+// float and subtract here: the error is the error of the ratio only.
+// Special cases:
+// DiffFromOne(1)         = +0
+// DiffFromOne(+/-Inf)    = +/-Inf
+// DiffFromOne(NaN)       = NaN
+//
+func DiffFromOne(x float64) float64 {
+	return x - 1
+}
+
+// RelativeToOne returns the signed distance of x from 1.0 in ulps,
+// the number of floats from 1 to x, negative for x < 1.
+//
+// It is UlpsBetween(x, 1.0) with the sign of x - 1. Below 1 the floats are
+// twice as dense: RelativeToOne(1 + 2^-52) = 1, but RelativeToOne(1 - 2^-52)
+// = -2. A convergence test of a ratio can stop when abs(RelativeToOne(r))
+// is small. From -4 down the distance is 2^63 or more and the result
+// saturates to math.MinInt64.
+// Special cases:
+// RelativeToOne(1)       = 0
+// RelativeToOne(+/-0)    = -0x3ff0000000000000
+// RelativeToOne(x <= -4) = math.MinInt64
+// RelativeToOne(-Inf)    = math.MinInt64
+// RelativeToOne(NaN)     = math.MaxInt64
+//
+func RelativeToOne(x float64) int64 {
+	if x != x {
+		return math.MaxInt64
+	}
+	d := UlpsBetween(x, 1.0)
+	switch {
+	case x >= 1:
+		return int64(d)
+	case d > math.MaxInt64:
+		return math.MinInt64
+	}
+	return -int64(d)
+}
+
+// UlpsToOverflow returns the headroom of x before overflow, the number of
+// steps away from zero from x to +/-MaxFloat64. One more step is +/-Inf.
+//
+// It is UlpsBetween(abs(x), MaxFloat64), the count of NextLargerMagnitude
+// steps which x takes without overflowing.
+// Special cases:
+// UlpsToOverflow(+/-MaxFloat64)  = 0
+// UlpsToOverflow(+/-Inf)         = 0
+// UlpsToOverflow(+/-0)           = 0x7fefffffffffffff, all positive finite floats
+// UlpsToOverflow(NaN)            = maxUint64
+//
+func UlpsToOverflow(x float64) uint64 {
+	u := math.Float64bits(x) &^ signbit
+	switch {
+	case u > posInf:
+		return maxUint64
+	case u == posInf:
+		return 0
+	}
+	return posInf - 1 - u
+}
+
+// InvertMonotone returns the x in [lo, hi] with f(x) nearest to y in ulps,
+// for f monotone on [lo, hi], increasing or decreasing.
+//
+// As FindFlip, the search halves the interval in ordinals, at most 64
+// steps. It finds the neighbours x and next x where f passes y and returns
+// the one with f nearer to y, the first on a tie. If y is not between
+// f(lo) and f(hi), the nearer end is returned. The direction is taken from
+// f(lo) and f(hi). Where f equals y at many x, the last of them is returned
+// for an increasing f. For a non-monotone f the result is a point where f
+// passes y, not necessarily the nearest one. NaN results of f compare
+// as false and mislead the search.
+// Special cases:
+// InvertMonotone(f, y, lo, hi)   = NaN    lo > hi
+// InvertMonotone(f, NaN, lo, hi) = NaN
+// InvertMonotone(f, y, NaN, hi)  = NaN
+//
+//	x := InvertMonotone(math.Exp, 2, 0, 1)    // math.Exp(x) nearest to 2
+//
+func InvertMonotone(f func(float64) float64, y, lo, hi float64) float64 {
+	a, b := ordinal(lo), ordinal(hi)
+	if lo != lo || hi != hi || y != y || a > b {
+		return math.NaN()
+	}
+	inc := f(lo) <= f(hi)
+	past := func(x float64) bool {            // f(x) is past y
+		if inc {
+			return f(x) > y
+		}
+		return f(x) < y
+	}
+	switch {
+	case past(lo):
+		return lo
+	case !past(hi):
+		return hi
+	}
+	for uint64(b-a) > 1 {
+		m := a + int64(uint64(b-a)/2)
+		if past(fromOrdinal(m)) {
+			b = m
+		} else {
+			a = m
+		}
+	}
+	x, z := fromOrdinal(a), fromOrdinal(b)
+	if a == ordinal(lo) {
+		x = lo
+	}
+	if b == ordinal(hi) {
+		z = hi
+	}
+	if UlpsBetween(f(z), y) < UlpsBetween(f(x), y) {
+		return z
+	}
+	return x
+}
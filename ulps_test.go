@@ -0,0 +1,466 @@
+package fbits
+
+import (
+	"math"
+	"testing"
+)
+
+func BenchmarkUlpsForAbsTol(b *testing.B) {
+	var u uint64
+	for n := 0; n < b.N; n++ {
+		u = UlpsForAbsTol(float64(n), 1e-3)
+	}
+	usink = u
+}
+
+func BenchmarkIsApproxInverse(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		x := float64(n + 1)
+		is = IsApproxInverse(x, 1/x, 1)
+	}
+	bsink = is
+}
+
+func BenchmarkSubUlps(b *testing.B) {
+	var u int64
+	for n := 0; n < b.N; n++ {
+		u, _ = SubUlps(float64(n|1<<20), 0x1p20)
+	}
+	isink = int(u)
+}
+
+func BenchmarkInUlpNeighborhood(b *testing.B) {
+	var is bool
+	for n := 0; n < b.N; n++ {
+		is = InUlpNeighborhood(1000, float64(n), 10)
+	}
+	bsink = is
+}
+
+func BenchmarkUlpsBetweenMagnitude(b *testing.B) {
+	var u uint64
+	for n := 0; n < b.N; n++ {
+		u = UlpsBetweenMagnitude(float64(n), -1000)
+	}
+	usink = u
+}
+
+func BenchmarkRelativeToOne(b *testing.B) {
+	var u int64
+	for n := 0; n < b.N; n++ {
+		u = RelativeToOne(1 + float64(n)*0x1p-40)
+	}
+	isink = int(u)
+}
+
+func BenchmarkUlpsToOverflow(b *testing.B) {
+	var u uint64
+	for n := 0; n < b.N; n++ {
+		u = UlpsToOverflow(float64(n))
+	}
+	usink = u
+}
+
+func BenchmarkInvertMonotone(b *testing.B) {
+	var x float64
+	for n := 0; n < b.N; n++ {
+		x = InvertMonotone(math.Exp, float64(n%100+1), -1000, 1000)
+	}
+	fsink = x
+}
+
+// ------------------------------------------------------------- Tests
+func TestDriftTracker(t *testing.T) {
+	var d DriftTracker
+	x := 1.0
+	d.Add(x)
+	want := 0.0
+	steps := []int{1, -3, 5, 0, -2, 7}
+	for _, k := range steps {
+		for i := 0; i < k; i++ {
+			x = NextFromZero(x)
+		}
+		for i := 0; i > k; i-- {
+			x = NextToZero(x)
+		}
+		d.Add(x)
+		if k < 0 {
+			k = -k
+		}
+		want += float64(k)
+	}
+	t.Logf("TotalUlps    %v", d.TotalUlps())
+	if d.TotalUlps() != want {
+		t.Fatalf("TotalUlps %v, want %v", d.TotalUlps(), want)
+	}
+	d = DriftTracker{}
+	d.Add(-0x1p-1074)
+	d.Add(0x1p-1074)
+	if d.TotalUlps() != 2 {
+		t.Fatalf("TotalUlps across zero %v", d.TotalUlps())
+	}
+}
+
+func TestUlpsForAbsTol(t *testing.T) {
+	const rounds int = 1e7
+	inf, nan := math.Inf(1), math.NaN()
+	t.Logf("1, 2^-52     %v", UlpsForAbsTol(1, 0x1p-52))
+	t.Logf("1, 1e-3      %v", UlpsForAbsTol(1, 1e-3))
+	t.Logf("0, 1         %v", UlpsForAbsTol(0, 1))
+	t.Logf("1, -1        %v", UlpsForAbsTol(1, -1))
+	t.Logf("1, Inf       %v", UlpsForAbsTol(1, inf))
+	t.Logf("Inf, 1       %v", UlpsForAbsTol(inf, 1))
+	t.Logf("NaN, 1       %v", UlpsForAbsTol(nan, 1))
+	t.Logf("1, NaN       %v", UlpsForAbsTol(1, nan))
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		at := math.Abs(RandomFloat64(&state))
+		k := Splitmix(&state) & (1<<20 - 1)
+		u := Ulp(at)
+		absTol := (float64(k) + 0.5) * u
+		n := UlpsForAbsTol(at, absTol)
+		if n != k && u > 0x1p-1000 {
+			t.Logf("i    %d", i)
+			t.Logf("at   %v", at)
+			t.Fatalf("n    %d, want %d", n, k)
+		}
+		// Moving at away from zero by absTol moves it n ulps within the binade.
+		y := at + float64(n)*u
+		if Ulp(y) == u && UlpsBetween(at, y) != n {
+			t.Logf("i    %d", i)
+			t.Logf("at   %v", at)
+			t.Fatalf("UlpsBetween %d, want %d", UlpsBetween(at, y), n)
+		}
+	}
+}
+
+func TestIsApproxInverse(t *testing.T) {
+	inf := math.Inf(1)
+	t.Logf("3 1/3          %v  (%d ulps)", IsApproxInverse(3, 1.0/3, 2), UlpsBetween(3*(1.0/3), 1))
+	t.Logf("10 1/10        %v", IsApproxInverse(10, 0.1, 2))
+	if !IsApproxInverse(3, 1.0/3, 2) || !IsApproxInverse(1.0/3, 3, 2) {
+		t.Fatalf("3 and 1/3 are not inverses")
+	}
+	if !IsApproxInverse(49, 1.0/49, 2) || !IsApproxInverse(-0.1, -10, 2) {
+		t.Fatalf("49 or -0.1 failed")
+	}
+	bad := [][2]float64{{3, 0.3}, {2, 2}, {-2, 0.5}, {0, inf}, {inf, 0}, {0x1p-1074, math.MaxFloat64}, {math.NaN(), 1}}
+	for _, p := range bad {
+		if IsApproxInverse(p[0], p[1], 1<<40) {
+			t.Fatalf("IsApproxInverse(%v, %v) is true", p[0], p[1])
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < 1e6; i++ {
+		x := RandomFloat64(&state)
+		if abs(x) < 0x1p-1020 || abs(x) > 0x1p1020 {
+			continue
+		}
+		if !IsApproxInverse(x, 1/x, 2) {
+			t.Fatalf("x %v, 1/x %v", x, 1/x)
+		}
+	}
+}
+
+func TestSubUlps(t *testing.T) {
+	const rounds int = 1e6
+	up, down := NextFromZero, NextToZero
+	cases := []struct {
+		x, y  float64
+		ulps  int64
+		exact bool
+	}{
+		{1, 1, 0, true},
+		{up(1), 1, 1, true},
+		{1, up(1), -1, true},
+		{1, down(1), 0, false},           // 1/2 ulp(1)
+		{down(2), 1, 0x1p52 - 1, true},
+		{2, 1, 0x1p51, true},
+		{2, down(2), 0, false},
+		{up(2), down(2), 1, false},       // 1.5 ulp(2)
+		{4, up(1), 0x1p50*3 - 1, false},  // 3 - 2^-52 in ulps of 4 (2^-50)
+		{1, -1, 0x1p53, true},
+		{up(1), -1, 0x1p53 + 1, false},   // 2 + 2^-52 rounds
+		{-0x1p-1074, 0x1p-1074, -2, true},
+		{math.MaxFloat64, -math.MaxFloat64, 0x1p54 - 2, false},   // overflow
+		{1, math.Inf(1), 0, false},
+		{math.NaN(), 1, 0, false},
+	}
+	for _, c := range cases {
+		u, e := SubUlps(c.x, c.y)
+		t.Logf("%-24v %-24v %-20d %v", c.x, c.y, u, e)
+		if u != c.ulps || e != c.exact {
+			t.Fatalf("SubUlps(%v, %v) = %d, %v, want %d, %v", c.x, c.y, u, e, c.ulps, c.exact)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		y := math.Float64frombits(math.Float64bits(x) ^ Splitmix(&state) & (1<<52 - 1))    // same binade
+		u, e := SubUlps(x, y)
+		if u != ordinal(x) - ordinal(y) || !e {
+			t.Logf("i    %d", i)
+			t.Logf("x    %v", x)
+			t.Fatalf("y    %v", y)
+		}
+	}
+}
+
+func TestInUlpNeighborhood(t *testing.T) {
+	const radius = 5
+	inf := math.Inf(1)
+	for _, c := range []float64{1, -1, 0, 0x1p-1074, -3 * 0x1p-1074, math.MaxFloat64, 0x1p-1022} {
+		// Step out from center in both directions, the first radius steps
+		// are in the neighbourhood, the next one is not.
+		for _, dir := range []float64{inf, -inf} {
+			x := c
+			for k := 0; k <= radius + 1; k++ {
+				if in := InUlpNeighborhood(c, x, radius); in != (k <= radius) && !IsInf(x) {
+					t.Fatalf("center %v, x %v, step %d: %v", c, x, k, in)
+				}
+				x = NextToward(x, dir)
+			}
+		}
+	}
+	if !InUlpNeighborhood(math.MaxFloat64, inf, 1) || InUlpNeighborhood(-0x1p-1074, 0x1p-1074, 1) {
+		t.Fatalf("Inf or zero crossing")
+	}
+	if InUlpNeighborhood(math.NaN(), 1, maxUint64) || InUlpNeighborhood(1, math.NaN(), maxUint64) {
+		t.Fatalf("NaN in neighbourhood")
+	}
+}
+
+func TestUlpsBetweenMagnitude(t *testing.T) {
+	const rounds int = 1e7
+	zero, inf, nan := 0.0, math.Inf(1), math.NaN()
+	cases := []struct {
+		x, y float64
+		want uint64
+	}{
+		{-1, 1, 0},
+		{-zero, zero, 0},
+		{-0x1p-1074, 0x1p-1074, 0},
+		{-0x1p-1074, 2 * 0x1p-1074, 1},
+		{-inf, inf, 0},
+		{-inf, math.MaxFloat64, 1},
+		{1, NextFromZero(-1), 1},
+		{nan, 1, maxUint64},
+		{-inf, nan, maxUint64},
+	}
+	for _, c := range cases {
+		m := UlpsBetweenMagnitude(c.x, c.y)
+		t.Logf("%-24v %-24v %20d %20d", c.x, c.y, m, UlpsBetween(c.x, c.y))
+		if m != c.want || UlpsBetweenMagnitude(c.y, c.x) != c.want {
+			t.Fatalf("UlpsBetweenMagnitude(%v, %v) = %d", c.x, c.y, m)
+		}
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		y := RandomFloat64(&state)
+		if i&1 == 0 {
+			y = -NextFromZero(x)
+		}
+		if m := UlpsBetweenMagnitude(x, y); m != UlpsBetween(math.Abs(x), math.Abs(y)) {
+			t.Logf("x    %v", x)
+			t.Logf("y    %v", y)
+			t.Fatalf("%d, UlpsBetween %d", m, UlpsBetween(math.Abs(x), math.Abs(y)))
+		}
+	}
+}
+
+func TestDampedNewtonStep(t *testing.T) {
+	const rounds int = 1e6
+	inf := math.Inf(1)
+	t.Logf("1 + 1e-10, 1000 ulps   %v", DampedNewtonStep(1, 1e-10, 1000))
+	t.Logf("1 + 1e-20, 1000 ulps   %v", DampedNewtonStep(1, 1e-20, 1000))
+	t.Logf("1e-320 - 1, 10 ulps    %v", DampedNewtonStep(1e-320, -1, 10))
+	if y := DampedNewtonStep(1, 1e-10, 1000); UlpsBetween(1, y) != 1000 || y <= 1 {
+		t.Fatalf("clamped up: %v", y)
+	}
+	if y := DampedNewtonStep(1, -1e10, 1000); UlpsBetween(1, y) != 1000 || y >= 1 {
+		t.Fatalf("clamped down: %v", y)
+	}
+	if y := DampedNewtonStep(3*0x1p-1074, -1, 5); y != -2*0x1p-1074 {
+		t.Fatalf("zero crossing: %v", y)
+	}
+	if DampedNewtonStep(-math.MaxFloat64, -inf, 3) != -inf || DampedNewtonStep(math.MaxFloat64, 1e300, maxUint64) != inf ||
+		DampedNewtonStep(2, 0, 0) != 2 || DampedNewtonStep(2, 1, 0) != 2 ||
+		!IsNaN(DampedNewtonStep(2, math.NaN(), 10)) || !IsNaN(DampedNewtonStep(math.NaN(), 1, 10)) {
+		t.Fatalf("special cases")
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		delta := math.Ldexp(RandomFloat64(&state), Log2(x)-40+int(Splitmix(&state)%60))
+		n := Splitmix(&state) >> (Splitmix(&state) % 64)
+		y := DampedNewtonStep(x, delta, n)
+		d := UlpsBetween(x, y)
+		if d > n || d < n && y != x+delta || delta > 0 && y < x || delta < 0 && y > x {
+			t.Logf("x      %v", x)
+			t.Logf("delta  %v  %d ulps", delta, n)
+			t.Fatalf("y      %v  %d ulps", y, d)
+		}
+	}
+}
+
+func TestFindFlip(t *testing.T) {
+	inf := math.Inf(1)
+	steps := 0
+	x := FindFlip(1, 2, func(x float64) bool { steps++; return x*x >= 2 })
+	t.Logf("x*x >= 2      %v, %d calls", x, steps)
+	if !(x*x < 2) || !(NextFromZero(x)*NextFromZero(x) >= 2) {
+		t.Fatalf("x*x >= 2: %v", x)
+	}
+	steps = 0
+	x = FindFlip(-inf, inf, func(x float64) bool { steps++; return x*x*x >= 2 })
+	t.Logf("x*x*x >= 2    %v, %d calls", x, steps)
+	if !(x*x*x < 2) || !(NextFromZero(x)*NextFromZero(x)*NextFromZero(x) >= 2) || steps > 66 {
+		t.Fatalf("x*x*x >= 2: %v", x)
+	}
+	x = FindFlip(-1, 1, func(x float64) bool { return x > 0 })
+	if x != 0 {
+		t.Fatalf("x > 0: %v", x)
+	}
+	x = FindFlip(-1, 1, func(x float64) bool { return x >= 0 })
+	if x != -0x1p-1074 {
+		t.Fatalf("x >= 0: %v", x)
+	}
+	x = FindFlip(-inf, inf, func(x float64) bool { return x > math.MaxFloat64 })
+	if x != math.MaxFloat64 {
+		t.Fatalf("x > MaxFloat64: %v", x)
+	}
+	pos := func(x float64) bool { return x > 0 }
+	if !IsNaN(FindFlip(1, 2, pos)) || !IsNaN(FindFlip(-2, -1, pos)) || !IsNaN(FindFlip(2, -1, pos)) ||
+		!IsNaN(FindFlip(math.NaN(), 1, pos)) || FindFlip(math.Copysign(0, -1), 1, pos) != 0 {
+		t.Fatalf("special cases")
+	}
+	if x := FindFlip(math.Copysign(0, -1), 1, pos); !math.Signbit(x) {
+		t.Fatalf("-0 lo: %v", x)
+	}
+}
+
+func TestDiffFromOne(t *testing.T) {
+	inf := math.Inf(1)
+	up, down := NextFromZero(1.0), NextToZero(1.0)
+	cases := []struct {
+		x    float64
+		diff float64
+		ulps int64
+	}{
+		{1, 0, 0},
+		{up, 0x1p-52, 1},
+		{NextFromZero(up), 0x1p-51, 2},
+		{down, -0x1p-53, -1},
+		{NextToZero(down), -0x1p-52, -2},
+		{2, 1, 1 << 52},
+		{0.5, -0.5, -1 << 52},
+		{0, -1, -0x3ff0000000000000},
+		{math.Copysign(0, -1), -1, -0x3ff0000000000000},
+		{-1, -2, -2 * 0x3ff0000000000000},
+		{inf, inf, 0x7ff0000000000000 - 0x3ff0000000000000},
+		{NextToZero(-4), -5, -math.MaxInt64},
+		{-4, -5, math.MinInt64},
+		{-5, -6, math.MinInt64},
+		{-math.MaxFloat64, -math.MaxFloat64, math.MinInt64},
+		{-inf, -inf, math.MinInt64},
+		{math.NaN(), math.NaN(), math.MaxInt64},
+	}
+	for _, c := range cases {
+		d, u := DiffFromOne(c.x), RelativeToOne(c.x)
+		t.Logf("%-24v %-24v %d", c.x, d, u)
+		if d != c.diff && !(d != d && c.diff != c.diff) || u != c.ulps {
+			t.Fatalf("x = %v: DiffFromOne = %v, want %v; RelativeToOne = %d, want %d", c.x, d, c.diff, u, c.ulps)
+		}
+	}
+	x := 1.0
+	for i := 1; i <= 1000; i++ {
+		x = NextToZero(x)
+		if RelativeToOne(x) != -int64(i) || DiffFromOne(x) != -float64(i)*0x1p-53 {
+			t.Fatalf("%d below 1: %v %d", i, DiffFromOne(x), RelativeToOne(x))
+		}
+	}
+}
+
+func TestUlpsToOverflow(t *testing.T) {
+	const rounds int = 1e6
+	max, inf := math.MaxFloat64, math.Inf(1)
+	cases := []struct {
+		x    float64
+		want uint64
+	}{
+		{max, 0},
+		{-max, 0},
+		{NextToZero(max), 1},
+		{inf, 0},
+		{-inf, 0},
+		{0, 0x7fefffffffffffff},
+		{math.Copysign(0, -1), 0x7fefffffffffffff},
+		{0x1p1023, 1<<52 - 1},
+		{math.NaN(), maxUint64},
+	}
+	for _, c := range cases {
+		u := UlpsToOverflow(c.x)
+		t.Logf("%-24v %d", c.x, u)
+		if u != c.want {
+			t.Fatalf("UlpsToOverflow(%v) = %d, want %d", c.x, u, c.want)
+		}
+	}
+	if !IsInf(NextLargerMagnitude(max)) {
+		t.Fatalf("NextLargerMagnitude(MaxFloat64) = %v", NextLargerMagnitude(max))
+	}
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		x := RandomFloat64(&state)
+		u := UlpsToOverflow(x)
+		if u != UlpsBetween(math.Abs(x), max) ||
+			u > 0 && UlpsToOverflow(NextLargerMagnitude(x)) != u-1 {
+			t.Fatalf("UlpsToOverflow(%v) = %d", x, u)
+		}
+	}
+}
+
+func TestInvertMonotone(t *testing.T) {
+	const rounds int = 1e4
+	x := InvertMonotone(math.Exp, 2, 0, 1)
+	t.Logf("Exp(%v) = %v, Exp(Ln2) = %v", x, math.Exp(x), math.Exp(math.Ln2))
+	if UlpsBetween(x, math.Ln2) > 1 || UlpsBetween(math.Exp(x), 2) > UlpsBetween(math.Exp(math.Ln2), 2) {
+		t.Fatalf("InvertMonotone(Exp, 2) = %v, want %v", x, math.Ln2)
+	}
+	cube := func(x float64) float64 { return x * x * x }
+	if x := InvertMonotone(cube, 27, -1e100, 1e100); x != 3 {
+		t.Fatalf("InvertMonotone(cube, 27) = %v", x)
+	}
+	neg := func(x float64) float64 { return -2 * x }
+	if x := InvertMonotone(neg, 5, -10, 10); x != -2.5 {
+		t.Fatalf("InvertMonotone(-2x, 5) = %v", x)
+	}
+	if x := InvertMonotone(math.Exp, 1e10, 0, 1); x != 1 {
+		t.Fatalf("y above f(hi): %v", x)
+	}
+	if x := InvertMonotone(math.Exp, -1, 0, 1); x != 0 {
+		t.Fatalf("y below f(lo): %v", x)
+	}
+	if x := InvertMonotone(math.Exp, math.NaN(), 0, 1); x == x {
+		t.Fatalf("y NaN: %v", x)
+	}
+	if x := InvertMonotone(math.Exp, 2, 1, 0); x == x {
+		t.Fatalf("lo > hi: %v", x)
+	}
+	// The inverse of Sqrt to ulp precision: x nearest to y^2.
+	state := uint64(1)
+	for i := 0; i < rounds; i++ {
+		y := math.Abs(RandomFloat64(&state))
+		x := InvertMonotone(math.Sqrt, y, 0, math.MaxFloat64)
+		d := UlpsBetween(math.Sqrt(x), y)
+		for _, z := range []float64{NextToZero(x), NextFromZero(x)} {
+			if z <= math.MaxFloat64 && UlpsBetween(math.Sqrt(z), y) < d {
+				t.Fatalf("InvertMonotone(Sqrt, %v) = %v, %v is nearer", y, x, z)
+			}
+		}
+		if y < 1e154 && y > 1e-153 && d != 0 {     // y^2 is a normal float
+			t.Fatalf("InvertMonotone(Sqrt, %v) = %v, sqrt %v", y, x, math.Sqrt(x))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package fbits
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// RunUlpTests checks f against the reference ref at the inputs. Each input
+// where UlpsBetween(f(x), ref(x)) > maxUlps is reported with t.Errorf,
+// the input and both results in decimal and hex and the distance in ulps.
+//
+// At most 20 inputs are reported one by one, then the number of failed
+// inputs and the worst input are reported. The worst case is also logged
+// with t.Logf when all inputs pass. Results which are both NaN agree.
+//
+//	RunUlpTests(t, myExp, math.Exp, inputs, 1)
+//
+func RunUlpTests(t testing.TB, f, ref func(float64) float64, inputs []float64, maxUlps uint64) {
+	t.Helper()
+	const maxReports = 20
+	var worstUlps uint64
+	worst := math.NaN()
+	failed := 0
+	for i, x := range inputs {
+		got, want := f(x), ref(x)
+		var d uint64
+		if got == got || want == want {
+			d = UlpsBetween(got, want)
+		}
+		if d > worstUlps || i == 0 {
+			worstUlps, worst = d, x
+		}
+		if d <= maxUlps {
+			continue
+		}
+		failed++
+		if failed <= maxReports {
+			t.Errorf("x %v (%x): got %v (%x), want %v (%x), %s",
+				x, x, got, got, want, want, FormatUlps(d))
+		}
+	}
+	if failed > 0 {
+		t.Errorf("%d of %d inputs over %s, worst x %v (%x) %s",
+			failed, len(inputs), FormatUlps(maxUlps), worst, worst, FormatUlps(worstUlps))
+		return
+	}
+	t.Logf("%d inputs, worst x %v (%x) %s", len(inputs), worst, worst, FormatUlps(worstUlps))
+}
+
+// FormatUlps returns ulps for log output: "0 ulps (exact)", "1 ulp",
+// "25 ulps", and from 10^6 on 2 significant digits, "1.2e6 ulps".
+// maxUint64, the saturated result of UlpsBetween for NaNs, is
+// "≥2^64 ulps (saturated)".
+func FormatUlps(ulps uint64) string {
+	switch {
+	case ulps == 0:
+		return "0 ulps (exact)"
+	case ulps == 1:
+		return "1 ulp"
+	case ulps == maxUint64:
+		return "≥2^64 ulps (saturated)"
+	}
+	return formatCount(ulps) + " ulps"
+}
+
+// FormatSignedUlps is FormatUlps for signed distances, as returned by
+// SubUlps and RelativeToOne: "-1 ulp", "-1.2e6 ulps". There is no
+// saturated case, math.MinInt64 is "-9.2e18 ulps".
+func FormatSignedUlps(ulps int64) string {
+	switch {
+	case ulps == 0:
+		return "0 ulps (exact)"
+	case ulps == 1:
+		return "1 ulp"
+	case ulps == -1:
+		return "-1 ulp"
+	case ulps < 0:
+		return "-" + formatCount(uint64(-ulps)) + " ulps"      // uint64(-MinInt64) = 2^63
+	}
+	return formatCount(uint64(ulps)) + " ulps"
+}
+
+// formatCount formats n in decimal below 10^6, otherwise as 1.2e6.
+func formatCount(n uint64) string {
+	if n < 1e6 {
+		return strconv.FormatUint(n, 10)
+	}
+	s := strconv.FormatFloat(float64(n), 'e', 1, 64)     // 1.2e+06
+	i := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[i+1:])
+	return s[:i+1] + strconv.Itoa(exp)
+}
+
+// AssertDistinct reports with t.Errorf each value which is bit-equal to an
+// earlier one, with both indexes. All NaNs are equal, a NaN is canonicalized
+// before comparing, but +0 and -0 are distinct.
+//
+// It guards a test against comparing a value to itself by accident, when
+// building inputs which should all be different.
+//
+//	AssertDistinct(t, x, NextFromZero(x), NextToZero(x))
+//
+func AssertDistinct(t testing.TB, values ...float64) {
+	t.Helper()
+	first := make(map[uint64]int, len(values))
+	for i, x := range values {
+		u := math.Float64bits(x)
+		if x != x {
+			u = math.Float64bits(math.NaN())
+		}
+		if j, ok := first[u]; ok {
+			t.Errorf("values %d and %d are equal, %v (%x)", j, i, x, x)
+			continue
+		}
+		first[u] = i
+	}
+}
@@ -0,0 +1,124 @@
+package fbits
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// recordTB records the messages of RunUlpTests.
+type recordTB struct {
+	testing.TB
+	errors, logs []string
+}
+
+func (r *recordTB) Helper() {}
+
+func (r *recordTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordTB) Logf(format string, args ...any) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+// ------------------------------------------------------------- Tests
+func TestRunUlpTests(t *testing.T) {
+	inputs := make([]float64, 1000)
+	state := uint64(1)
+	for i := range inputs {
+		inputs[i] = math.Abs(RandomFloat64(&state))
+	}
+	inputs[0] = math.NaN()
+	sqrt := func(x float64) float64 { return math.Pow(x, 0.5) }
+
+	r := &recordTB{TB: t}
+	RunUlpTests(r, sqrt, math.Sqrt, inputs, 1)
+	t.Logf("good: %v", r.logs)
+	if len(r.errors) != 0 || len(r.logs) != 1 {
+		t.Fatalf("good pair: %v", r.errors)
+	}
+
+	r = &recordTB{TB: t}
+	bad := func(x float64) float64 { return NextFromZero(NextFromZero(math.Sqrt(x))) }
+	RunUlpTests(r, bad, math.Sqrt, inputs, 1)
+	for _, e := range r.errors[:2] {
+		t.Logf("bad:  %s", e)
+	}
+	t.Logf("bad:  %s", r.errors[len(r.errors)-1])
+	if len(r.errors) != 21 || len(r.logs) != 0 {
+		t.Fatalf("bad pair: %d errors", len(r.errors))
+	}
+
+	r = &recordTB{TB: t}
+	RunUlpTests(r, bad, math.Sqrt, inputs, 2)
+	if len(r.errors) != 0 {
+		t.Fatalf("bad pair, 2 ulps: %v", r.errors)
+	}
+}
+
+func TestFormatUlps(t *testing.T) {
+	cases := []struct {
+		ulps uint64
+		want string
+	}{
+		{0, "0 ulps (exact)"},
+		{1, "1 ulp"},
+		{2, "2 ulps"},
+		{999999, "999999 ulps"},
+		{1e6, "1.0e6 ulps"},
+		{1234567, "1.2e6 ulps"},
+		{1 << 52, "4.5e15 ulps"},
+		{maxUint64 - 1, "1.8e19 ulps"},
+		{maxUint64, "≥2^64 ulps (saturated)"},
+	}
+	for _, c := range cases {
+		if s := FormatUlps(c.ulps); s != c.want {
+			t.Fatalf("FormatUlps(%d) = %q, want %q", c.ulps, s, c.want)
+		}
+	}
+	signed := []struct {
+		ulps int64
+		want string
+	}{
+		{0, "0 ulps (exact)"},
+		{1, "1 ulp"},
+		{-1, "-1 ulp"},
+		{-2, "-2 ulps"},
+		{-1234567, "-1.2e6 ulps"},
+		{math.MaxInt64, "9.2e18 ulps"},
+		{math.MinInt64, "-9.2e18 ulps"},
+	}
+	for _, c := range signed {
+		if s := FormatSignedUlps(c.ulps); s != c.want {
+			t.Fatalf("FormatSignedUlps(%d) = %q, want %q", c.ulps, s, c.want)
+		}
+	}
+	t.Logf("%s, %s", FormatUlps(UlpsBetween(1, 2)), FormatSignedUlps(RelativeToOne(0.5)))
+}
+
+func TestAssertDistinct(t *testing.T) {
+	nz := math.Copysign(0, -1)
+	cases := []struct {
+		values []float64
+		errors int
+	}{
+		{nil, 0},
+		{[]float64{1}, 0},
+		{[]float64{1, NextFromZero(1), NextToZero(1), 0, nz, math.NaN(), math.Inf(1)}, 0},
+		{[]float64{1, 2, 1}, 1},
+		{[]float64{1, 1, 1}, 2},
+		{[]float64{math.NaN(), math.Float64frombits(0xfff0000000000001)}, 1},
+		{[]float64{0, nz, 0, nz}, 2},
+	}
+	for _, c := range cases {
+		r := &recordTB{TB: t}
+		AssertDistinct(r, c.values...)
+		t.Logf("%v  %v", c.values, r.errors)
+		if len(r.errors) != c.errors {
+			t.Fatalf("AssertDistinct(%v): %d errors, want %d", c.values, len(r.errors), c.errors)
+		}
+	}
+	x := 0.1
+	AssertDistinct(t, x, NextFromZero(x), NextToZero(x), -x)
+}